@@ -0,0 +1,236 @@
+package ann
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrUnknownActivation is the error for when an activation function is
+	// requested by a name that isn't registered
+	ErrUnknownActivation = errors.New("Unknown activation function")
+)
+
+// Activation is a nonlinearity applied to the total input of a neuron. The
+// derivative is taken with respect to that same total input
+type Activation interface {
+	Activate(x float64) float64
+	Derivative(x float64) float64
+	Name() string
+}
+
+// ActivationByName returns the built-in activation registered under the given
+// name, using the default parameters for any that are configurable
+func ActivationByName(name string) (Activation, error) {
+	switch name {
+	case "algebraic_sigmoid":
+		return &AlgebraicSigmoid{}, nil
+	case "elu":
+		return NewELU(1.0), nil
+	case "identity":
+		return &Identity{}, nil
+	case "leaky_relu":
+		return NewLeakyReLU(0.01), nil
+	case "logistic":
+		return &Logistic{}, nil
+	case "relu":
+		return &ReLU{}, nil
+	case "softplus":
+		return &Softplus{}, nil
+	case "tanh":
+		return &Tanh{}, nil
+	}
+
+	return nil, ErrUnknownActivation
+}
+
+// AlgebraicSigmoid is the algebraic sigmoid x / sqrt(1 + x^2), ranging over
+// (-1, 1)
+type AlgebraicSigmoid struct{}
+
+// Activate applies the algebraic sigmoid
+func (a *AlgebraicSigmoid) Activate(x float64) float64 {
+	return Sigmoid(x)
+}
+
+// Derivative returns the slope of the algebraic sigmoid at x
+func (a *AlgebraicSigmoid) Derivative(x float64) float64 {
+	return math.Pow(1+x*x, -1.5)
+}
+
+// Name returns the registered name of the activation
+func (a *AlgebraicSigmoid) Name() string {
+	return "algebraic_sigmoid"
+}
+
+// ELU is the exponential linear unit, which is linear for positive values and
+// smoothly saturates to -Alpha for negative values
+type ELU struct {
+	Alpha float64 `json:"alpha"`
+}
+
+// NewELU creates a new exponential linear unit with the given saturation value
+func NewELU(alpha float64) *ELU {
+	return &ELU{Alpha: alpha}
+}
+
+// Activate applies the exponential linear unit
+func (a *ELU) Activate(x float64) float64 {
+	if x > 0 {
+		return x
+	}
+
+	return a.Alpha * (math.Exp(x) - 1)
+}
+
+// Derivative returns the slope of the exponential linear unit at x
+func (a *ELU) Derivative(x float64) float64 {
+	if x > 0 {
+		return 1
+	}
+
+	return a.Alpha * math.Exp(x)
+}
+
+// Name returns the registered name of the activation
+func (a *ELU) Name() string {
+	return "elu"
+}
+
+// Identity passes the total input through unchanged
+type Identity struct{}
+
+// Activate returns x unchanged
+func (a *Identity) Activate(x float64) float64 {
+	return x
+}
+
+// Derivative returns the slope of the identity, which is always 1
+func (a *Identity) Derivative(x float64) float64 {
+	return 1
+}
+
+// Name returns the registered name of the activation
+func (a *Identity) Name() string {
+	return "identity"
+}
+
+// LeakyReLU is a rectified linear unit that lets a small, Alpha scaled signal
+// through for negative values
+type LeakyReLU struct {
+	Alpha float64 `json:"alpha"`
+}
+
+// NewLeakyReLU creates a new leaky rectified linear unit with the given slope
+// for negative values
+func NewLeakyReLU(alpha float64) *LeakyReLU {
+	return &LeakyReLU{Alpha: alpha}
+}
+
+// Activate applies the leaky rectified linear unit
+func (a *LeakyReLU) Activate(x float64) float64 {
+	if x > 0 {
+		return x
+	}
+
+	return a.Alpha * x
+}
+
+// Derivative returns the slope of the leaky rectified linear unit at x
+func (a *LeakyReLU) Derivative(x float64) float64 {
+	if x > 0 {
+		return 1
+	}
+
+	return a.Alpha
+}
+
+// Name returns the registered name of the activation
+func (a *LeakyReLU) Name() string {
+	return "leaky_relu"
+}
+
+// Logistic is the e based logistic sigmoid, ranging over (0, 1)
+type Logistic struct{}
+
+// Activate applies the logistic sigmoid
+func (a *Logistic) Activate(x float64) float64 {
+	return sigmoid(x)
+}
+
+// Derivative returns the slope of the logistic sigmoid at x
+func (a *Logistic) Derivative(x float64) float64 {
+	return sigmoidP(x)
+}
+
+// Name returns the registered name of the activation
+func (a *Logistic) Name() string {
+	return "logistic"
+}
+
+// ReLU is the rectified linear unit max(0, x)
+type ReLU struct{}
+
+// Activate applies the rectified linear unit
+func (a *ReLU) Activate(x float64) float64 {
+	return math.Max(0, x)
+}
+
+// Derivative returns the slope of the rectified linear unit at x
+func (a *ReLU) Derivative(x float64) float64 {
+	if x > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// Name returns the registered name of the activation
+func (a *ReLU) Name() string {
+	return "relu"
+}
+
+// Softplus is the smooth approximation of the rectified linear unit,
+// ln(1 + e^x)
+type Softplus struct{}
+
+// Activate applies the softplus function
+func (a *Softplus) Activate(x float64) float64 {
+	// Avoid overflowing the exponent for large inputs, where softplus is
+	// effectively linear anyway
+	if x > 30 {
+		return x
+	}
+
+	return math.Log1p(math.Exp(x))
+}
+
+// Derivative returns the slope of the softplus function at x, which is the
+// logistic sigmoid
+func (a *Softplus) Derivative(x float64) float64 {
+	return sigmoid(x)
+}
+
+// Name returns the registered name of the activation
+func (a *Softplus) Name() string {
+	return "softplus"
+}
+
+// Tanh is the hyperbolic tangent, ranging over (-1, 1)
+type Tanh struct{}
+
+// Activate applies the hyperbolic tangent
+func (a *Tanh) Activate(x float64) float64 {
+	return math.Tanh(x)
+}
+
+// Derivative returns the slope of the hyperbolic tangent at x
+func (a *Tanh) Derivative(x float64) float64 {
+	t := math.Tanh(x)
+	return 1 - t*t
+}
+
+// Name returns the registered name of the activation
+func (a *Tanh) Name() string {
+	return "tanh"
+}
@@ -0,0 +1,51 @@
+package ann
+
+import (
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestActivations(suite *testing.T) {
+	names := []string{"algebraic_sigmoid", "elu", "identity", "leaky_relu",
+		"logistic", "relu", "softplus", "tanh"}
+
+	this.Should("Look up each built-in activation by name", suite,
+		func() {
+			for _, name := range names {
+				activation, err := ActivationByName(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(activation.Name()).To(Equal(name))
+			}
+
+			_, err := ActivationByName("nope")
+			Expect(err).To(Equal(ErrUnknownActivation))
+		})
+
+	this.Should("Provide derivatives that match a finite difference estimate", suite,
+		func() {
+			epsilon := 1e-6
+			for _, name := range names {
+				activation, _ := ActivationByName(name)
+
+				// Stay clear of zero where the rectifiers have a kink
+				for _, x := range []float64{-2.5, -0.7, -0.1, 0.3, 1.2, 4.0} {
+					numeric := (activation.Activate(x+epsilon) -
+						activation.Activate(x-epsilon)) / (2 * epsilon)
+					Expect(activation.Derivative(x)).To(BeNumerically("~", numeric, 1e-6))
+				}
+			}
+		})
+
+	this.Should("Keep the expected output ranges", suite,
+		func() {
+			Expect((&Logistic{}).Activate(0)).To(Equal(0.5))
+			Expect((&Tanh{}).Activate(0)).To(Equal(0.0))
+			Expect((&ReLU{}).Activate(-3)).To(Equal(0.0))
+			Expect(NewLeakyReLU(0.1).Activate(-3)).To(BeNumerically("~", -0.3, 1e-12))
+			Expect(NewELU(1.0).Activate(-100)).To(BeNumerically("~", -1.0, 1e-12))
+			Expect((&Softplus{}).Activate(100)).To(Equal(100.0))
+			Expect((&AlgebraicSigmoid{}).Activate(3)).To(BeNumerically("<", 1.0))
+		})
+}
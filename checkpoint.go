@@ -0,0 +1,133 @@
+package ann
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	// CheckpointVersion is the version of the checkpoint format written by
+	// SaveCheckpoint
+	CheckpointVersion = 1
+)
+
+// checkpoint is a network saved along with the optimizer training it
+type checkpoint struct {
+	Version   int                  `json:"version"`
+	Network   *NeuralNetwork       `json:"network"`
+	Optimizer *optimizerCheckpoint `json:"optimizer,omitempty"`
+	Progress  *TrainingProgress    `json:"progress,omitempty"`
+
+	// RandomDraws is how many values had been drawn from the network's random
+	// source, so it can carry on from the same point
+	RandomDraws uint64 `json:"random_draws"`
+}
+
+type optimizerCheckpoint struct {
+	Name   string          `json:"name"`
+	Params json.RawMessage `json:"params"`
+	State  *OptimizerState `json:"state"`
+}
+
+// TrainingProgress is how far training had come when a checkpoint was saved
+type TrainingProgress struct {
+	// Iteration is the number of iterations trained so far
+	Iteration int `json:"iteration"`
+
+	// Schedule is the state of a StatefulSchedule, if training used one
+	Schedule json.RawMessage `json:"schedule,omitempty"`
+}
+
+// Progress returns how far training has come, to be saved with a checkpoint
+func (t *TrainingConfiguration) Progress() (*TrainingProgress, error) {
+	progress := &TrainingProgress{Iteration: t.Iteration}
+	if schedule, ok := t.Schedule.(StatefulSchedule); ok {
+		state, err := schedule.GetState()
+		if err != nil {
+			return nil, err
+		}
+		progress.Schedule = state
+	}
+
+	return progress, nil
+}
+
+// Resume picks training back up from the progress saved with a checkpoint. A
+// nil progress leaves the configuration as it is
+func (t *TrainingConfiguration) Resume(progress *TrainingProgress) error {
+	if progress == nil {
+		return nil
+	}
+
+	t.Iteration = progress.Iteration
+	if schedule, ok := t.Schedule.(StatefulSchedule); ok && progress.Schedule != nil {
+		return schedule.LoadState(progress.Schedule)
+	}
+
+	return nil
+}
+
+// SaveCheckpoint writes the network, the full state of its optimizer and the
+// position of its random source to the writer as JSON, along with the training
+// progress, so training can later be resumed exactly. The optimizer and the
+// progress may be nil to leave them out
+func SaveCheckpoint(w io.Writer, network *NeuralNetwork, optimizer Optimizer, progress *TrainingProgress) error {
+	dst := checkpoint{
+		Version:     CheckpointVersion,
+		Network:     network,
+		Progress:    progress,
+		RandomDraws: network.randomDraws(),
+	}
+
+	if optimizer != nil {
+		params, err := json.Marshal(optimizer)
+		if err != nil {
+			return err
+		}
+
+		dst.Optimizer = &optimizerCheckpoint{
+			Name:   optimizer.Name(),
+			Params: params,
+			State:  optimizer.GetState(network),
+		}
+	}
+
+	return json.NewEncoder(w).Encode(dst)
+}
+
+// LoadCheckpoint reads a checkpoint written by SaveCheckpoint, returning the
+// network, with its random source where it was, its optimizer and the training
+// progress. The optimizer and progress are nil if they weren't saved
+func LoadCheckpoint(r io.Reader) (*NeuralNetwork, Optimizer, *TrainingProgress, error) {
+	src := checkpoint{Network: &NeuralNetwork{}}
+	if err := json.NewDecoder(r).Decode(&src); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if src.Version > CheckpointVersion {
+		return nil, nil, nil, fmt.Errorf("Unsupported checkpoint version %d", src.Version)
+	}
+	src.Network.setRandomDraws(src.RandomDraws)
+
+	if src.Optimizer == nil {
+		return src.Network, nil, src.Progress, nil
+	}
+
+	optimizer, err := OptimizerByName(src.Optimizer.Name, 0)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%v %q", err, src.Optimizer.Name)
+	}
+
+	if err := json.Unmarshal(src.Optimizer.Params, optimizer); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if src.Optimizer.State != nil {
+		if err := optimizer.LoadState(src.Network, src.Optimizer.State); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return src.Network, optimizer, src.Progress, nil
+}
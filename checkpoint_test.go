@@ -0,0 +1,98 @@
+package ann
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestCheckpoint(suite *testing.T) {
+	inputs := []*InputConfiguration{
+		&InputConfiguration{Values: [][]float64{{0, 0}, {1, 0}}, Expected: [][]float64{{0, 1}}, Weight: 1},
+		&InputConfiguration{Values: [][]float64{{1, 0}, {0, 1}}, Expected: [][]float64{{1, 0}}, Weight: 1},
+		&InputConfiguration{Values: [][]float64{{0, 1}, {1, 1}}, Expected: [][]float64{{1, 1}}, Weight: 1},
+		&InputConfiguration{Values: [][]float64{{1, 1}, {0, 0}}, Expected: [][]float64{{0, 0}}, Weight: 1},
+	}
+
+	newConfig := func(network *NeuralNetwork, optimizer Optimizer) *TrainingConfiguration {
+		return &TrainingConfiguration{
+			BatchSize:          2,
+			Engine:             NewGradientEngineWithOptimizer(optimizer),
+			Inputs:             inputs,
+			Network:            network,
+			Sampling:           SamplingEpoch,
+			Schedule:           NewWarmupSchedule(4, NewReduceOnPlateauSchedule(0.5, 1)),
+			Validation:         inputs[:2],
+			ValidationInterval: 2,
+		}
+	}
+
+	newNetwork := func() *NeuralNetwork {
+		return newTestNetwork(7,
+			LayerConfiguration{Width: 2, Height: 2, Activation: &Identity{}},
+			LayerConfiguration{Width: 4, Height: 4, Activation: &Tanh{}, Dropout: 0.25,
+				Initializer: &XavierInitializer{}},
+			LayerConfiguration{Width: 1, Height: 2, Activation: &Logistic{}})
+	}
+
+	save := func(network NetworkConfiguration) []byte {
+		buf := &bytes.Buffer{}
+		Expect(network.(*NeuralNetwork).SaveBinary(buf, PrecisionFloat64)).To(Succeed())
+		return buf.Bytes()
+	}
+
+	this.Should("Train 10 and then 10 more iterations from a checkpoint exactly like 20 straight", suite,
+		func() {
+			straight := newConfig(newNetwork(), NewAdam(0.1))
+			straightHistory, err := straight.Engine.Train(20, straight)
+			Expect(err).ToNot(HaveOccurred())
+
+			first := newConfig(newNetwork(), NewAdam(0.1))
+			_, err = first.Engine.Train(10, first)
+			Expect(err).ToNot(HaveOccurred())
+
+			progress, err := first.Progress()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(progress.Iteration).To(Equal(10))
+			Expect(progress.Schedule).ToNot(BeEmpty())
+
+			buf := &bytes.Buffer{}
+			optimizer := first.Engine.(*GradientEngine).Optimizer
+			Expect(SaveCheckpoint(buf, first.Network.(*NeuralNetwork), optimizer, progress)).To(Succeed())
+
+			network, loaded, loadedProgress, err := LoadCheckpoint(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loadedProgress).To(Equal(progress))
+
+			resumed := newConfig(network, loaded)
+			Expect(resumed.Resume(loadedProgress)).To(Succeed())
+			history, err := resumed.Engine.Train(10, resumed)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(save(resumed.Network)).To(Equal(save(straight.Network)))
+			Expect(history.Records[0].Iteration).To(Equal(10))
+			for i, record := range history.Records {
+				expected := straightHistory.Records[10+i]
+				Expect(record.LearningRate).To(Equal(expected.LearningRate))
+				Expect(record.TrainingLoss).To(Equal(expected.TrainingLoss))
+			}
+		})
+
+	this.Should("Put the random source back where it was", suite,
+		func() {
+			network := newNetwork()
+			network.GetRand().Float64()
+			network.GetRand().Perm(5)
+
+			buf := &bytes.Buffer{}
+			Expect(SaveCheckpoint(buf, network, nil, nil)).To(Succeed())
+			loaded, optimizer, progress, err := LoadCheckpoint(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(optimizer).To(BeNil())
+			Expect(progress).To(BeNil())
+
+			Expect(loaded.GetRand().Int63()).To(Equal(network.GetRand().Int63()))
+		})
+}
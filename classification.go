@@ -0,0 +1,254 @@
+package ann
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// ClassificationReport measures how well a network classifies a labeled set
+// of inputs. Networks with a single output neuron are binary classifiers, with
+// class 1 for outputs of at least 0.5 and class 0 otherwise. For larger output
+// layers each neuron is a class, numbered row * height + column, and the
+// network picks the class of its ArgMax
+type ClassificationReport struct {
+	Accuracy float64         `json:"accuracy"`
+	Classes  []*ClassMetrics `json:"classes"`
+
+	// Confusion counts the inputs of each expected class, by row, that were
+	// classified as each class, by column
+	Confusion [][]int `json:"confusion"`
+	Correct   int     `json:"correct"`
+	Total     int     `json:"total"`
+
+	// ROC and AUC are only calculated for binary classifiers, and are left
+	// empty unless both classes appear in the expected values
+	AUC float64     `json:"auc,omitempty"`
+	ROC []*ROCPoint `json:"roc,omitempty"`
+}
+
+// ClassMetrics are the metrics of a single class
+type ClassMetrics struct {
+	Class     int     `json:"class"`
+	F1        float64 `json:"f1"`
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+
+	// Support is the number of inputs expected to be in the class
+	Support int `json:"support"`
+}
+
+// ROCPoint is a point of a receiver operating characteristic curve, the rates
+// of true and false positives when classifying outputs of at least Threshold
+// as positive
+type ROCPoint struct {
+	FalsePositiveRate float64 `json:"false_positive_rate"`
+	Threshold         float64 `json:"threshold"`
+	TruePositiveRate  float64 `json:"true_positive_rate"`
+}
+
+// EvaluateClassification runs the network over every input and measures how
+// well it classifies them
+func EvaluateClassification(network NetworkConfiguration, inputs []*InputConfiguration) (*ClassificationReport, error) {
+	output := network.GetOutput()
+	classes := output.Width() * output.Height()
+	binary := classes == 1
+	if binary {
+		classes = 2
+	}
+
+	report := &ClassificationReport{
+		Classes:   make([]*ClassMetrics, classes),
+		Confusion: make([][]int, classes),
+		Total:     len(inputs),
+	}
+	for i := range report.Confusion {
+		report.Confusion[i] = make([]int, classes)
+	}
+
+	scores := make([]float64, 0, len(inputs))
+	labels := make([]bool, 0, len(inputs))
+	for i, input := range inputs {
+		if err := network.Run(input.Values); err != nil {
+			return nil, fmt.Errorf("Input %d: %v", i, err)
+		}
+
+		if len(input.Expected) != output.Width() ||
+			len(input.Expected[0]) != output.Height() {
+			return nil, fmt.Errorf("Input %d: %v", i, ErrArraySizeMismatch)
+		}
+
+		expected := expectedClass(input.Expected)
+		predicted := predictedClass(output)
+		report.Confusion[expected][predicted]++
+		if expected == predicted {
+			report.Correct++
+		}
+
+		if binary {
+			scores = append(scores, output.Neurons[0][0].Potential)
+			labels = append(labels, expected == 1)
+		}
+	}
+
+	if report.Total > 0 {
+		report.Accuracy = float64(report.Correct) / float64(report.Total)
+	}
+
+	for class := range report.Classes {
+		metrics := &ClassMetrics{Class: class}
+		predicted := 0
+		for expected := range report.Confusion {
+			predicted += report.Confusion[expected][class]
+			metrics.Support += report.Confusion[class][expected]
+		}
+
+		hits := float64(report.Confusion[class][class])
+		if predicted > 0 {
+			metrics.Precision = hits / float64(predicted)
+		}
+		if metrics.Support > 0 {
+			metrics.Recall = hits / float64(metrics.Support)
+		}
+		if metrics.Precision+metrics.Recall > 0 {
+			metrics.F1 = 2 * metrics.Precision * metrics.Recall /
+				(metrics.Precision + metrics.Recall)
+		}
+
+		report.Classes[class] = metrics
+	}
+
+	if binary {
+		report.ROC, report.AUC = rocCurve(scores, labels)
+	}
+
+	return report, nil
+}
+
+// expectedClass returns the class of a grid of expected values
+func expectedClass(expected [][]float64) int {
+	if len(expected) == 1 && len(expected[0]) == 1 {
+		if expected[0][0] >= 0.5 {
+			return 1
+		}
+
+		return 0
+	}
+
+	best := Position{}
+	for row := range expected {
+		for column, val := range expected[row] {
+			if val > expected[best.Row][best.Column] {
+				best = Position{Row: row, Column: column}
+			}
+		}
+	}
+
+	return best.Row*len(expected[0]) + best.Column
+}
+
+// predictedClass returns the class the output layer picked
+func predictedClass(output *NetworkLayer) int {
+	if output.Width()*output.Height() == 1 {
+		if output.Neurons[0][0].Potential >= 0.5 {
+			return 1
+		}
+
+		return 0
+	}
+
+	best := output.ArgMax()
+	return best.Row*output.Height() + best.Column
+}
+
+// rocCurve returns the ROC curve of the scores of a binary classifier, from
+// the highest threshold to the lowest, along with the area under it
+func rocCurve(scores []float64, labels []bool) ([]*ROCPoint, float64) {
+	positives, negatives := 0, 0
+	for _, label := range labels {
+		if label {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+
+	if positives == 0 || negatives == 0 {
+		return nil, 0
+	}
+
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	curve := []*ROCPoint{&ROCPoint{Threshold: scores[order[0]] + 1}}
+	truePositives, falsePositives := 0, 0
+	area := 0.0
+	for i, index := range order {
+		if labels[index] {
+			truePositives++
+		} else {
+			falsePositives++
+		}
+
+		// Inputs with the same score cross the threshold together
+		if i < len(order)-1 && scores[order[i+1]] == scores[index] {
+			continue
+		}
+
+		point := &ROCPoint{
+			FalsePositiveRate: float64(falsePositives) / float64(negatives),
+			Threshold:         scores[index],
+			TruePositiveRate:  float64(truePositives) / float64(positives),
+		}
+
+		prev := curve[len(curve)-1]
+		area += (point.FalsePositiveRate - prev.FalsePositiveRate) *
+			(point.TruePositiveRate + prev.TruePositiveRate) / 2
+		curve = append(curve, point)
+	}
+
+	return curve, area
+}
+
+// WriteJSON writes the report as JSON
+func (r *ClassificationReport) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// WriteText writes the report as a human readable table
+func (r *ClassificationReport) WriteText(w io.Writer) error {
+	out := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(out, "accuracy: %.4f (%d/%d)\n\n", r.Accuracy, r.Correct, r.Total)
+
+	fmt.Fprintln(out, "class\tprecision\trecall\tf1\tsupport")
+	for _, class := range r.Classes {
+		fmt.Fprintf(out, "%d\t%.4f\t%.4f\t%.4f\t%d\n",
+			class.Class, class.Precision, class.Recall, class.F1, class.Support)
+	}
+
+	fmt.Fprintln(out, "\nconfusion matrix (rows are expected, columns are predicted):")
+	for class := range r.Confusion {
+		fmt.Fprintf(out, "\t%d", class)
+	}
+	fmt.Fprintln(out)
+	for class, row := range r.Confusion {
+		fmt.Fprintf(out, "%d", class)
+		for _, count := range row {
+			fmt.Fprintf(out, "\t%d", count)
+		}
+		fmt.Fprintln(out)
+	}
+
+	if r.ROC != nil {
+		fmt.Fprintf(out, "\nauc: %.4f\n", r.AUC)
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,124 @@
+package ann
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestClassification(suite *testing.T) {
+	// newPassthrough builds a network whose output is its input, so the inputs
+	// are the scores being classified
+	newPassthrough := func(width, height int) *NeuralNetwork {
+		network := NewNeuralNetwork(0, 0, 0)
+		network.AddConfiguredLayer(LayerConfiguration{
+			Width: width, Height: height, Activation: &Identity{}})
+		network.AddConfiguredLayer(LayerConfiguration{
+			Width: width, Height: height, Activation: &Identity{},
+			Connectivity: ConnectOneToOne, Initializer: NewConstantInitializer(1)})
+
+		return network
+	}
+
+	newInput := func(values, expected []float64) *InputConfiguration {
+		return &InputConfiguration{
+			Values:   [][]float64{values},
+			Expected: [][]float64{expected},
+			Weight:   1,
+		}
+	}
+
+	binaryInputs := func() []*InputConfiguration {
+		scores := []float64{0.9, 0.8, 0.7, 0.6, 0.55, 0.4}
+		labels := []float64{1, 1, 0, 1, 0, 0}
+		inputs := make([]*InputConfiguration, len(scores))
+		for i := range scores {
+			inputs[i] = newInput([]float64{scores[i]}, []float64{labels[i]})
+		}
+
+		return inputs
+	}
+
+	this.Should("Measure a binary classifier", suite,
+		func() {
+			report, err := EvaluateClassification(newPassthrough(1, 1), binaryInputs())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(report.Correct).To(Equal(4))
+			Expect(report.Total).To(Equal(6))
+			Expect(report.Accuracy).To(BeNumerically("~", 4.0/6, 1e-12))
+			Expect(report.Confusion).To(Equal([][]int{[]int{1, 2}, []int{0, 3}}))
+
+			Expect(report.Classes[0].Precision).To(Equal(1.0))
+			Expect(report.Classes[0].Recall).To(BeNumerically("~", 1.0/3, 1e-12))
+			Expect(report.Classes[0].F1).To(BeNumerically("~", 0.5, 1e-12))
+			Expect(report.Classes[1].Precision).To(BeNumerically("~", 0.6, 1e-12))
+			Expect(report.Classes[1].Recall).To(Equal(1.0))
+			Expect(report.Classes[1].Support).To(Equal(3))
+
+			// Eight of the nine positive and negative pairs are ranked correctly
+			Expect(report.AUC).To(BeNumerically("~", 8.0/9, 1e-12))
+			Expect(report.ROC).To(HaveLen(7))
+			Expect(*report.ROC[0]).To(Equal(ROCPoint{Threshold: 1.9}))
+			last := report.ROC[len(report.ROC)-1]
+			Expect(last.TruePositiveRate).To(Equal(1.0))
+			Expect(last.FalsePositiveRate).To(Equal(1.0))
+		})
+
+	this.Should("Cross tied scores together on the ROC curve", suite,
+		func() {
+			curve, auc := rocCurve([]float64{0.5, 0.5, 0.5, 0.5}, []bool{true, false, true, false})
+			Expect(curve).To(HaveLen(2))
+			Expect(auc).To(Equal(0.5))
+
+			curve, auc = rocCurve([]float64{0.5, 0.2}, []bool{true, true})
+			Expect(curve).To(BeNil())
+			Expect(auc).To(Equal(0.0))
+		})
+
+	this.Should("Measure a multi-class classifier by the ArgMax of its output", suite,
+		func() {
+			inputs := []*InputConfiguration{
+				newInput([]float64{0.7, 0.2, 0.1}, []float64{1, 0, 0}),
+				newInput([]float64{0.1, 0.8, 0.1}, []float64{0, 1, 0}),
+				newInput([]float64{0.1, 0.6, 0.3}, []float64{0, 0, 1}),
+				newInput([]float64{0.2, 0.2, 0.6}, []float64{0, 0, 1}),
+			}
+
+			report, err := EvaluateClassification(newPassthrough(1, 3), inputs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Accuracy).To(Equal(0.75))
+			Expect(report.Confusion).To(Equal([][]int{
+				[]int{1, 0, 0}, []int{0, 1, 0}, []int{0, 1, 1}}))
+			Expect(report.Classes[1].Precision).To(Equal(0.5))
+			Expect(report.Classes[2].Recall).To(Equal(0.5))
+			Expect(report.ROC).To(BeNil())
+		})
+
+	this.Should("Reject expected values that don't match the output layer", suite,
+		func() {
+			inputs := []*InputConfiguration{newInput([]float64{0.5}, []float64{1, 0})}
+			_, err := EvaluateClassification(newPassthrough(1, 1), inputs)
+			Expect(err).To(HaveOccurred())
+		})
+
+	this.Should("Write the report as text and JSON", suite,
+		func() {
+			report, _ := EvaluateClassification(newPassthrough(1, 1), binaryInputs())
+
+			buf := &bytes.Buffer{}
+			Expect(report.WriteText(buf)).To(Succeed())
+			Expect(buf.String()).To(ContainSubstring("accuracy: 0.6667 (4/6)"))
+			Expect(buf.String()).To(ContainSubstring("auc: 0.8889"))
+			Expect(buf.String()).To(MatchRegexp(`1\s+0\.6000\s+1\.0000\s+0\.7500\s+3`))
+
+			buf.Reset()
+			Expect(report.WriteJSON(buf)).To(Succeed())
+			loaded := &ClassificationReport{}
+			Expect(json.Unmarshal(buf.Bytes(), loaded)).To(Succeed())
+			Expect(loaded).To(Equal(report))
+		})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/connerhansen/ann"
+)
+
+func runEvaluate(args []string, env *environment) error {
+	flags := newFlagSet("evaluate", env)
+	model := flags.String("model", "", "path to the trained model, .json for JSON")
+	data := flags.String("data", "", "JSON dataset of labeled input configurations")
+	jsonOutput := flags.Bool("json", false, "print the report as JSON instead of text")
+	regression := flags.Bool("regression", false, "report regression metrics and per neuron residuals instead of classification metrics")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *model == "" || *data == "" {
+		return usageError{fmt.Errorf("-model and -data are required")}
+	}
+
+	network, err := loadModel(*model)
+	if err != nil {
+		return err
+	}
+
+	inputs, err := loadDataset(*data)
+	if err != nil {
+		return err
+	}
+
+	if err := checkDataset(inputs, network); err != nil {
+		return err
+	}
+
+	var report interface {
+		WriteJSON(io.Writer) error
+		WriteText(io.Writer) error
+	}
+	if *regression {
+		report, err = ann.EvaluateRegression(network, inputs)
+	} else {
+		report, err = ann.EvaluateClassification(network, inputs)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if *jsonOutput {
+		return report.WriteJSON(env.stdout)
+	}
+
+	return report.WriteText(env.stdout)
+}
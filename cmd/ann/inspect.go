@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/connerhansen/ann"
+)
+
+func runInspect(args []string, env *environment) error {
+	flags := newFlagSet("inspect", env)
+	model := flags.String("model", "", "path to the trained model, .json for JSON")
+	dump := flags.String("dump", "", "also dump each layer's total_in or total_out grid")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *model == "" {
+		return usageError{fmt.Errorf("-model is required")}
+	}
+
+	if *dump != "" && *dump != "total_in" && *dump != "total_out" {
+		return usageError{fmt.Errorf("-dump must be total_in or total_out")}
+	}
+
+	network, err := loadModel(*model)
+	if err != nil {
+		return err
+	}
+
+	inspect(network, *dump, env.stdout)
+	return nil
+}
+
+// inspect prints the seed of the network and the shape, activation and weight
+// statistics of each layer of the network, optionally followed by a dump of the given field
+func inspect(network *ann.NeuralNetwork, dump string, w io.Writer) {
+	fmt.Fprintf(w, "layers: %d\n", network.GetDepth())
+	fmt.Fprintf(w, "seed: %d\n", network.GetSeed())
+
+	for i, layer := range network.GetLayers() {
+		activation := "binary"
+		if layer.Activation != nil {
+			activation = layer.Activation.Name()
+		}
+		if layer.Softmax != nil {
+			activation += " + " + layer.Softmax.Name()
+			if layer.Softmax.PerRow {
+				activation += " per row"
+			}
+		}
+
+		inhibitory := 0
+		weights := &statistics{}
+		biases := &statistics{}
+		layer.EachNeuron(func(n *ann.Neuron) {
+			if n.Type == ann.TypeInhibitory {
+				inhibitory++
+			}
+
+			biases.add(n.Bias)
+			for _, conn := range n.In {
+				weights.add(conn.Weight)
+			}
+		})
+
+		fmt.Fprintf(w, "\nlayer %d: %dx%d %s, %d inhibitory\n",
+			i, layer.Width(), layer.Height(), activation, inhibitory)
+		fmt.Fprintf(w, "  incoming weights: %s\n", weights)
+		fmt.Fprintf(w, "  biases:           %s\n", biases)
+		if layer.Dropout > 0 || layer.DropConnect > 0 {
+			fmt.Fprintf(w, "  dropout:          %g, drop connect %g\n", layer.Dropout, layer.DropConnect)
+		}
+		if r := layer.Regularization; r != nil {
+			fmt.Fprintf(w, "  regularization:   l1=%g l2=%g max_norm=%g\n", r.L1, r.L2, r.MaxNorm)
+		}
+
+		if dump != "" {
+			layer.Fprint(w, dump)
+			io.WriteString(w, "\n")
+		}
+	}
+}
+
+// statistics tracks the summary statistics of a set of values
+type statistics struct {
+	count int
+	min   float64
+	max   float64
+	sum   float64
+	sumSq float64
+}
+
+func (s *statistics) add(val float64) {
+	if s.count == 0 || val < s.min {
+		s.min = val
+	}
+
+	if s.count == 0 || val > s.max {
+		s.max = val
+	}
+
+	s.count++
+	s.sum += val
+	s.sumSq += val * val
+}
+
+func (s *statistics) String() string {
+	if s.count == 0 {
+		return "count=0"
+	}
+
+	mean := s.sum / float64(s.count)
+	variance := math.Max(0, s.sumSq/float64(s.count)-mean*mean)
+
+	return fmt.Sprintf("count=%d min=%.4f max=%.4f mean=%.4f std=%.4f",
+		s.count, s.min, s.max, mean, math.Sqrt(variance))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/connerhansen/ann"
+	"github.com/connerhansen/colog"
+)
+
+// Exit codes, so scripts can tell bad invocations apart from failed runs
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// Loggers
+var (
+	Debug *log.Logger
+	Info  *log.Logger
+	Warn  *log.Logger
+	Error *log.Logger
+)
+
+// environment holds the streams a command reads from and writes to
+type environment struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// command is a single ann subcommand
+type command struct {
+	run     func(args []string, env *environment) error
+	summary string
+}
+
+// usageError is the error for when a command was invoked incorrectly
+type usageError struct {
+	error
+}
+
+var commands = map[string]command{
+	"evaluate": {runEvaluate, "report how well a model classifies a labeled dataset"},
+	"inspect":  {runInspect, "print the layers and weight statistics of a model"},
+	"predict":  {runPredict, "run inputs through a trained model"},
+	"train":    {runTrain, "build and train a network, then save the model"},
+}
+
+func main() {
+	initLoggers()
+
+	os.Exit(run(os.Args[1:], &environment{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}))
+}
+
+// run dispatches to the requested subcommand and returns the exit code
+func run(args []string, env *environment) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(env.stderr)
+		if len(args) == 0 {
+			return exitUsage
+		}
+
+		return exitOK
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(env.stderr, "ann: unknown command %q\n", args[0])
+		printUsage(env.stderr)
+		return exitUsage
+	}
+
+	if err := cmd.run(args[1:], env); err != nil {
+		fmt.Fprintf(env.stderr, "ann %s: %v\n", args[0], err)
+		if _, ok := err.(usageError); ok {
+			return exitUsage
+		}
+
+		return exitFailure
+	}
+
+	return exitOK
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: ann <command> [flags]")
+	fmt.Fprintln(w, "\ncommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-8s %s\n", name, commands[name].summary)
+	}
+
+	fmt.Fprintln(w, "\nRun 'ann <command> -h' for the flags of a command.")
+}
+
+func initLoggers() {
+	colog.SetLoggingLevel(colog.LogLevelDebug)
+	Debug, Info, Warn, Error = colog.SetupLoggers()
+	ann.SetLoggers(Debug, Info, Warn, Error)
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/connerhansen/ann"
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func init() {
+	RegisterFailHandler(this.GomegaFailHandler)
+}
+
+func TestCommands(t *testing.T) {
+	newEnv := func(stdin string) (*environment, *bytes.Buffer, *bytes.Buffer) {
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+
+		return &environment{
+			stdin:  strings.NewReader(stdin),
+			stdout: stdout,
+			stderr: stderr,
+		}, stdout, stderr
+	}
+
+	writeDataset := func(dir string) string {
+		path := filepath.Join(dir, "data.json")
+		data := `[
+			{"values": [[0, 0], [0, 1]], "expected": [[0.2]]},
+			{"values": [[1, 1], [1, 0]], "expected": [[0.8]]}
+		]`
+		Expect(ioutil.WriteFile(path, []byte(data), 0644)).To(Succeed())
+
+		return path
+	}
+
+	this.Should("Exit with a usage error when no command is given", t,
+		func() {
+			env, _, stderr := newEnv("")
+			Expect(run(nil, env)).To(Equal(exitUsage))
+			Expect(stderr.String()).To(ContainSubstring("usage: ann"))
+
+			env, _, _ = newEnv("")
+			Expect(run([]string{"bogus"}, env)).To(Equal(exitUsage))
+		})
+
+	this.Should("Exit with a usage error when required flags are missing", t,
+		func() {
+			env, _, stderr := newEnv("")
+			Expect(run([]string{"train", "-layers", "2x2,1x1"}, env)).To(Equal(exitUsage))
+			Expect(stderr.String()).To(ContainSubstring("required"))
+
+			env, _, _ = newEnv("")
+			Expect(run([]string{"predict"}, env)).To(Equal(exitUsage))
+
+			env, _, stderr = newEnv("")
+			Expect(run([]string{"train", "-layers", "2x2,1x1", "-init", "magic",
+				"-data", "data.json", "-out", "model.bin"}, env)).To(Equal(exitUsage))
+			Expect(stderr.String()).To(ContainSubstring("Unknown weight initializer"))
+		})
+
+	this.Should("Exit with a failure when the model can't be loaded", t,
+		func() {
+			env, _, stderr := newEnv("")
+			Expect(run([]string{"inspect", "-model", "/does/not/exist"}, env)).To(Equal(exitFailure))
+			Expect(stderr.String()).To(ContainSubstring("ann inspect:"))
+		})
+
+	this.Should("Train, predict and inspect a model", t,
+		func() {
+			dir, err := ioutil.TempDir("", "ann")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			for _, name := range []string{"model.bin", "model.json"} {
+				model := filepath.Join(dir, name)
+				env, _, stderr := newEnv("")
+				code := run([]string{"train", "-layers", "2x2,3x3,1x1", "-data", writeDataset(dir),
+					"-out", model, "-iterations", "3000"}, env)
+				Expect(code).To(Equal(exitOK), stderr.String())
+
+				env, stdout, stderr := newEnv("[[0, 0], [0, 1]]\n[[1, 1], [1, 0]]\n")
+				Expect(run([]string{"predict", "-model", model}, env)).To(Equal(exitOK), stderr.String())
+
+				lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+				Expect(lines).To(HaveLen(2))
+
+				var first, second [][]float64
+				Expect(json.Unmarshal([]byte(lines[0]), &first)).To(Succeed())
+				Expect(json.Unmarshal([]byte(lines[1]), &second)).To(Succeed())
+				Expect(first[0][0]).To(BeNumerically("~", 0.2, 0.05))
+				Expect(second[0][0]).To(BeNumerically("~", 0.8, 0.05))
+
+				env, parallel, stderr := newEnv("[[0, 0], [0, 1]]\n[[1, 1], [1, 0]]\n")
+				Expect(run([]string{"predict", "-model", model, "-workers", "3"}, env)).To(Equal(exitOK), stderr.String())
+				Expect(parallel.String()).To(Equal(stdout.String()))
+
+				env, stdout, _ = newEnv("")
+				Expect(run([]string{"inspect", "-model", model, "-dump", "total_in"}, env)).To(Equal(exitOK))
+				Expect(stdout.String()).To(ContainSubstring("layer 1: 3x3 logistic"))
+				Expect(stdout.String()).To(ContainSubstring("incoming weights: count=36"))
+
+				env, stdout, stderr = newEnv("")
+				Expect(run([]string{"evaluate", "-model", model, "-data", writeDataset(dir)}, env)).To(
+					Equal(exitOK), stderr.String())
+				Expect(stdout.String()).To(ContainSubstring("accuracy: 1.0000 (2/2)"))
+				Expect(stdout.String()).To(ContainSubstring("auc: 1.0000"))
+
+				env, stdout, _ = newEnv("")
+				Expect(run([]string{"evaluate", "-model", model, "-data", writeDataset(dir), "-json"}, env)).To(
+					Equal(exitOK))
+				Expect(stdout.String()).To(ContainSubstring(`"accuracy":1`))
+
+				env, stdout, stderr = newEnv("")
+				Expect(run([]string{"evaluate", "-model", model, "-data", writeDataset(dir), "-regression"}, env)).To(
+					Equal(exitOK), stderr.String())
+				Expect(stdout.String()).To(ContainSubstring("rmse: "))
+				Expect(stdout.String()).To(ContainSubstring("residual mean"))
+			}
+		})
+
+	this.Should("Train a model from a spec file", t,
+		func() {
+			dir, _ := ioutil.TempDir("", "ann")
+			defer os.RemoveAll(dir)
+
+			spec := filepath.Join(dir, "spec.json")
+			Expect(ioutil.WriteFile(spec, []byte(`{
+				"layers": [
+					{"width": 2, "height": 2, "activation": "identity"},
+					{"width": 2, "height": 2, "activation": "tanh"},
+					{"width": 1, "height": 1, "activation": "logistic"}
+				],
+				"training": {"learning_rate": 0.5, "iterations": 2000}
+			}`), 0644)).To(Succeed())
+
+			model := filepath.Join(dir, "model.json")
+			env, _, stderr := newEnv("")
+			code := run([]string{"train", "-spec", spec, "-data", writeDataset(dir), "-out", model}, env)
+			Expect(code).To(Equal(exitOK), stderr.String())
+
+			env, stdout, _ := newEnv("")
+			Expect(run([]string{"inspect", "-model", model}, env)).To(Equal(exitOK))
+			Expect(stdout.String()).To(ContainSubstring("layer 1: 2x2 tanh"))
+
+			env, _, _ = newEnv("")
+			code = run([]string{"train", "-spec", spec, "-layers", "2x2,1x1",
+				"-data", writeDataset(dir), "-out", model}, env)
+			Expect(code).To(Equal(exitUsage))
+		})
+
+	this.Should("Produce identical models for the same seed", t,
+		func() {
+			dir, _ := ioutil.TempDir("", "ann")
+			defer os.RemoveAll(dir)
+
+			train := func(name, seed string) []byte {
+				model := filepath.Join(dir, name)
+				env, _, stderr := newEnv("")
+				code := run([]string{"train", "-layers", "2x2,3x3,1x1", "-init", "he",
+					"-activation", "tanh", "-inhibitory", "0.3", "-optimizer", "adam",
+					"-batch", "2", "-sampling", "epoch", "-data", writeDataset(dir),
+					"-out", model, "-iterations", "50", "-seed", seed}, env)
+				Expect(code).To(Equal(exitOK), stderr.String())
+
+				data, err := ioutil.ReadFile(model)
+				Expect(err).ToNot(HaveOccurred())
+				return data
+			}
+
+			first := train("first.bin", "42")
+			Expect(train("second.bin", "42")).To(Equal(first))
+			Expect(train("third.bin", "43")).ToNot(Equal(first))
+
+			env, stdout, _ := newEnv("")
+			Expect(run([]string{"inspect", "-model", filepath.Join(dir, "first.bin")}, env)).To(Equal(exitOK))
+			Expect(stdout.String()).To(ContainSubstring("seed: 42"))
+		})
+
+	this.Should("Train with dropout", t,
+		func() {
+			dir, _ := ioutil.TempDir("", "ann")
+			defer os.RemoveAll(dir)
+
+			model := filepath.Join(dir, "model.json")
+			env, _, stderr := newEnv("")
+			code := run([]string{"train", "-layers", "2x2,4x4,1x1", "-dropout", "0.25", "-drop-connect", "0.1",
+				"-data", writeDataset(dir), "-out", model, "-iterations", "50"}, env)
+			Expect(code).To(Equal(exitOK), stderr.String())
+
+			env, stdout, _ := newEnv("")
+			Expect(run([]string{"inspect", "-model", model}, env)).To(Equal(exitOK))
+			Expect(stdout.String()).To(ContainSubstring("dropout:          0.25, drop connect 0.1"))
+			Expect(stdout.String()).To(ContainSubstring("dropout:          0, drop connect 0.1"))
+
+			env, _, _ = newEnv("")
+			code = run([]string{"train", "-layers", "2x2,1x1", "-dropout", "1",
+				"-data", writeDataset(dir), "-out", model}, env)
+			Expect(code).To(Equal(exitUsage))
+
+			for _, flag := range []string{"-dropout", "-drop-connect"} {
+				env, _, stderr = newEnv("")
+				code = run([]string{"train", "-layers", "2x2,4x4,1x1", "-engine", "default", flag, "0.25",
+					"-data", writeDataset(dir), "-out", model}, env)
+				Expect(code).To(Equal(exitUsage), flag)
+				Expect(stderr.String()).To(ContainSubstring("doesn't support dropout"))
+			}
+		})
+
+	this.Should("Fail when training diverges, keeping the last good weights with -rollback", t,
+		func() {
+			dir, _ := ioutil.TempDir("", "ann")
+			defer os.RemoveAll(dir)
+
+			model := filepath.Join(dir, "model.bin")
+			args := []string{"train", "-layers", "2x2,3x3,1x1", "-activation", "identity", "-rate", "1e300",
+				"-data", writeDataset(dir), "-out", model, "-iterations", "100"}
+
+			env, _, stderr := newEnv("")
+			Expect(run(args, env)).To(Equal(exitFailure))
+			Expect(stderr.String()).To(ContainSubstring("Non-finite"))
+			_, err := os.Stat(model)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+
+			env, _, _ = newEnv("")
+			Expect(run(append(args, "-rollback"), env)).To(Equal(exitFailure))
+
+			network, err := loadModel(model)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ann.CheckWeights(network)).To(Succeed())
+
+			// The optimizer state is rolled back along with the weights, so it can
+			// still be checkpointed
+			checkpoint := filepath.Join(dir, "checkpoint.json")
+			env, _, stderr = newEnv("")
+			code := run(append(args, "-rollback", "-optimizer", "momentum", "-rate", "1e308",
+				"-checkpoint", checkpoint), env)
+			Expect(code).To(Equal(exitFailure))
+			Expect(stderr.String()).To(ContainSubstring("Non-finite"))
+			_, _, _, err = loadCheckpoint(checkpoint)
+			Expect(err).ToNot(HaveOccurred())
+
+			env, _, stderr = newEnv("")
+			code = run([]string{"train", "-layers", "2x2,1x1", "-clip-norm", "1", "-clip-value", "0.5",
+				"-data", writeDataset(dir), "-out", model, "-iterations", "10"}, env)
+			Expect(code).To(Equal(exitOK), stderr.String())
+
+			env, _, stderr = newEnv("")
+			code = run([]string{"train", "-layers", "2x2,1x1", "-engine", "default", "-clip-norm", "1",
+				"-data", writeDataset(dir), "-out", model, "-iterations", "10"}, env)
+			Expect(code).To(Equal(exitFailure))
+			Expect(stderr.String()).To(ContainSubstring("doesn't support gradient clipping"))
+
+			// The default engine rolls back too
+			Expect(os.Remove(model)).To(Succeed())
+			env, _, stderr = newEnv("")
+			Expect(run(append(args, "-engine", "default", "-rollback"), env)).To(Equal(exitFailure))
+			Expect(stderr.String()).To(ContainSubstring("Non-finite"))
+			network, err = loadModel(model)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ann.CheckWeights(network)).To(Succeed())
+		})
+
+	this.Should("Refuse batches with the default engine", t,
+		func() {
+			dir, _ := ioutil.TempDir("", "ann")
+			defer os.RemoveAll(dir)
+
+			for _, batch := range []string{"2", "-1"} {
+				env, _, stderr := newEnv("")
+				code := run([]string{"train", "-layers", "2x2,1x1", "-engine", "default", "-batch", batch,
+					"-sampling", "epoch", "-data", writeDataset(dir), "-out", filepath.Join(dir, "model.bin")}, env)
+				Expect(code).To(Equal(exitFailure), batch)
+				Expect(stderr.String()).To(ContainSubstring("doesn't support batches"))
+			}
+		})
+
+	this.Should("Regularize the hidden and output layers", t,
+		func() {
+			dir, _ := ioutil.TempDir("", "ann")
+			defer os.RemoveAll(dir)
+
+			model := filepath.Join(dir, "model.bin")
+			env, _, stderr := newEnv("")
+			code := run([]string{"train", "-layers", "2x2,3x3,1x1", "-l2", "0.01", "-max-norm", "2",
+				"-data", writeDataset(dir), "-out", model, "-iterations", "50"}, env)
+			Expect(code).To(Equal(exitOK), stderr.String())
+
+			env, stdout, _ := newEnv("")
+			Expect(run([]string{"inspect", "-model", model}, env)).To(Equal(exitOK))
+			Expect(stdout.String()).To(ContainSubstring("regularization:   l1=0 l2=0.01 max_norm=2"))
+			Expect(strings.Count(stdout.String(), "regularization:")).To(Equal(2))
+
+			env, _, _ = newEnv("")
+			code = run([]string{"train", "-layers", "2x2,1x1", "-l1", "-1",
+				"-data", writeDataset(dir), "-out", model}, env)
+			Expect(code).To(Equal(exitUsage))
+
+			env, _, stderr = newEnv("")
+			code = run([]string{"train", "-layers", "2x2,1x1", "-engine", "default", "-l2", "0.01",
+				"-data", writeDataset(dir), "-out", model}, env)
+			Expect(code).To(Equal(exitUsage))
+			Expect(stderr.String()).To(ContainSubstring("doesn't support regularization"))
+		})
+
+	this.Should("Validate on a held-out dataset while training", t,
+		func() {
+			dir, _ := ioutil.TempDir("", "ann")
+			defer os.RemoveAll(dir)
+
+			model := filepath.Join(dir, "model.bin")
+			history := filepath.Join(dir, "history.csv")
+			env, _, stderr := newEnv("")
+			code := run([]string{"train", "-layers", "2x2,3x3,1x1", "-data", writeDataset(dir),
+				"-validation", writeDataset(dir), "-patience", "5", "-min-delta", "0.0001",
+				"-out", model, "-iterations", "200", "-history", history}, env)
+			Expect(code).To(Equal(exitOK), stderr.String())
+
+			data, err := ioutil.ReadFile(history)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strings.HasPrefix(string(data), "iteration,training_loss,validation_loss")).To(BeTrue())
+
+			// The learning rate column follows the schedule
+			env, _, stderr = newEnv("")
+			code = run([]string{"train", "-layers", "2x2,3x3,1x1", "-data", writeDataset(dir),
+				"-rate", "0.4", "-schedule", "reduce_on_plateau", "-warmup", "4", "-validation-split", "0.5",
+				"-out", model, "-iterations", "10", "-history", history}, env)
+			Expect(code).To(Equal(exitOK), stderr.String())
+
+			data, err = ioutil.ReadFile(history)
+			Expect(err).ToNot(HaveOccurred())
+			records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(HaveLen(11))
+			Expect(records[1][4]).To(Equal("0.1"))
+			Expect(records[4][4]).To(Equal("0.4"))
+
+			env, _, stderr = newEnv("")
+			code = run([]string{"train", "-layers", "2x2,1x1", "-data", writeDataset(dir),
+				"-validation-split", "1", "-out", model}, env)
+			Expect(code).To(Equal(exitFailure))
+			Expect(stderr.String()).To(ContainSubstring("no inputs to train on"))
+		})
+
+	this.Should("Resume training from a checkpoint", t,
+		func() {
+			dir, _ := ioutil.TempDir("", "ann")
+			defer os.RemoveAll(dir)
+
+			checkpoint := filepath.Join(dir, "checkpoint.json")
+			env, _, stderr := newEnv("")
+			code := run([]string{"train", "-layers", "2x2,2x2,1x1", "-optimizer", "adam", "-rate", "0.05",
+				"-data", writeDataset(dir), "-out", filepath.Join(dir, "model.bin"),
+				"-checkpoint", checkpoint, "-iterations", "100"}, env)
+			Expect(code).To(Equal(exitOK), stderr.String())
+
+			model := filepath.Join(dir, "resumed.bin")
+			env, _, stderr = newEnv("")
+			code = run([]string{"train", "-resume", checkpoint, "-data", writeDataset(dir),
+				"-out", model, "-iterations", "100"}, env)
+			Expect(code).To(Equal(exitOK), stderr.String())
+
+			env, _, _ = newEnv("")
+			code = run([]string{"train", "-resume", checkpoint, "-layers", "2x2,1x1",
+				"-data", writeDataset(dir), "-out", model}, env)
+			Expect(code).To(Equal(exitUsage))
+
+			// Training 10 and resuming for 10 more ends up exactly where training 20
+			// straight does, warmup included
+			train := []string{"train", "-layers", "2x2,3x3,1x1", "-optimizer", "adam", "-rate", "0.1",
+				"-seed", "7", "-sampling", "epoch", "-warmup", "5", "-data", writeDataset(dir)}
+			straight := filepath.Join(dir, "straight.bin")
+			env, _, stderr = newEnv("")
+			code = run(append(train, "-out", straight, "-iterations", "20"), env)
+			Expect(code).To(Equal(exitOK), stderr.String())
+
+			env, _, stderr = newEnv("")
+			code = run(append(train, "-out", filepath.Join(dir, "half.bin"), "-checkpoint", checkpoint,
+				"-iterations", "10"), env)
+			Expect(code).To(Equal(exitOK), stderr.String())
+
+			env, _, stderr = newEnv("")
+			code = run([]string{"train", "-resume", checkpoint, "-sampling", "epoch", "-warmup", "5",
+				"-data", writeDataset(dir), "-out", model, "-iterations", "10"}, env)
+			Expect(code).To(Equal(exitOK), stderr.String())
+
+			expected, err := ioutil.ReadFile(straight)
+			Expect(err).ToNot(HaveOccurred())
+			actual, err := ioutil.ReadFile(model)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(Equal(expected))
+		})
+
+	this.Should("Reject inputs that don't fit the model", t,
+		func() {
+			dir, _ := ioutil.TempDir("", "ann")
+			defer os.RemoveAll(dir)
+
+			model := filepath.Join(dir, "model.bin")
+			env, _, _ := newEnv("")
+			run([]string{"train", "-layers", "2x2,1x1", "-data", writeDataset(dir),
+				"-out", model, "-iterations", "10"}, env)
+
+			env, _, stderr := newEnv("[[0, 0, 0]]")
+			Expect(run([]string{"predict", "-model", model}, env)).To(Equal(exitFailure))
+			Expect(stderr.String()).To(ContainSubstring("input 0"))
+		})
+}
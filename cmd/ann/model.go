@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/connerhansen/ann"
+)
+
+// newFlagSet creates a flag set for the named command that reports problems
+// as errors instead of exiting
+func newFlagSet(name string, env *environment) *flag.FlagSet {
+	flags := flag.NewFlagSet("ann "+name, flag.ContinueOnError)
+	flags.SetOutput(env.stderr)
+
+	return flags
+}
+
+// parseFlags parses the flags of a command, turning any failure into a usage
+// error
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return usageError{err}
+	}
+
+	if flags.NArg() > 0 {
+		return usageError{fmt.Errorf("unexpected arguments %v", flags.Args())}
+	}
+
+	return nil
+}
+
+// isJSONPath reports whether the model at path should use the JSON format
+// rather than the binary one
+func isJSONPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// loadModel loads a network from a model file, picking the format from the
+// file extension
+func loadModel(path string) (*ann.NeuralNetwork, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	network := &ann.NeuralNetwork{}
+	if isJSONPath(path) {
+		err = network.LoadJSON(file)
+	} else {
+		err = network.LoadBinary(file)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("loading model %s: %v", path, err)
+	}
+
+	if network.GetDepth() == 0 {
+		return nil, fmt.Errorf("model %s has no layers", path)
+	}
+
+	return network, nil
+}
+
+// saveModel saves a network to a model file, picking the format from the file
+// extension
+func saveModel(path string, network *ann.NeuralNetwork, precision int) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if isJSONPath(path) {
+		err = network.SaveJSON(file)
+	} else {
+		err = network.SaveBinary(file, precision)
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// loadCheckpoint loads a network, its optimizer and the training progress from
+// a checkpoint file
+func loadCheckpoint(path string) (*ann.NeuralNetwork, ann.Optimizer, *ann.TrainingProgress, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer file.Close()
+
+	network, optimizer, progress, err := ann.LoadCheckpoint(file)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("loading checkpoint %s: %v", path, err)
+	}
+
+	return network, optimizer, progress, nil
+}
+
+// saveCheckpoint saves a network, its optimizer and the training progress to a
+// checkpoint file
+func saveCheckpoint(path string, network *ann.NeuralNetwork, optimizer ann.Optimizer, progress *ann.TrainingProgress) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = ann.SaveCheckpoint(file, network, optimizer, progress)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// loadDataset reads a JSON array of input configurations. Inputs without a
+// weight are given a weight of 1 so they're sampled evenly
+func loadDataset(path string) ([]*ann.InputConfiguration, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	inputs := make([]*ann.InputConfiguration, 0)
+	if err := json.NewDecoder(file).Decode(&inputs); err != nil {
+		return nil, fmt.Errorf("reading dataset %s: %v", path, err)
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("dataset %s is empty", path)
+	}
+
+	for _, input := range inputs {
+		if input.Weight == 0 {
+			input.Weight = 1.0
+		}
+	}
+
+	return inputs, nil
+}
+
+// saveHistory writes a training history, picking the format from the file
+// extension
+func saveHistory(path string, history *ann.History) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if isJSONPath(path) {
+		err = history.WriteJSON(file)
+	} else {
+		err = history.WriteCSV(file)
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/connerhansen/ann"
+)
+
+func runPredict(args []string, env *environment) error {
+	flags := newFlagSet("predict", env)
+	model := flags.String("model", "", "path to the trained model, .json for JSON")
+	inputPath := flags.String("input", "-", "file of JSON input grids, - for stdin")
+	workers := flags.Int("workers", 1, "number of goroutines each layer is split across")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *model == "" {
+		return usageError{fmt.Errorf("-model is required")}
+	}
+
+	network, err := loadModel(*model)
+	if err != nil {
+		return err
+	}
+	network.SetWorkers(*workers)
+
+	var input io.Reader = env.stdin
+	if *inputPath != "-" {
+		file, err := os.Open(*inputPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	return predict(network, input, env.stdout)
+}
+
+// predict runs each JSON encoded input grid read from r through the network,
+// writing the output grid of each one to w as a line of JSON
+func predict(network *ann.NeuralNetwork, r io.Reader, w io.Writer) error {
+	decoder := json.NewDecoder(r)
+	encoder := json.NewEncoder(w)
+
+	for i := 0; ; i++ {
+		var values [][]float64
+		if err := decoder.Decode(&values); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("input %d: %v", i, err)
+		}
+
+		if !fitsLayer(values, network.GetInput()) {
+			return fmt.Errorf("input %d: values do not match the %dx%d input layer",
+				i, network.GetInput().Width(), network.GetInput().Height())
+		}
+
+		if err := network.Run(values); err != nil {
+			return fmt.Errorf("input %d: %v", i, err)
+		}
+
+		if err := encoder.Encode(outputGrid(network)); err != nil {
+			return err
+		}
+	}
+}
+
+// outputGrid copies the potentials of the network's output layer
+func outputGrid(network *ann.NeuralNetwork) [][]float64 {
+	output := network.GetOutput()
+	grid := make([][]float64, output.Width())
+	output.EachNeuronWithIndex(func(n *ann.Neuron, row, column int) {
+		grid[row] = append(grid[row], n.Potential)
+	})
+
+	return grid
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/connerhansen/ann"
+)
+
+func runTrain(args []string, env *environment) error {
+	flags := newFlagSet("train", env)
+	specPath := flags.String("spec", "", "JSON network spec describing the layers and training")
+	layers := flags.String("layers", "", "comma separated layer shapes, e.g. 3x3,5x5,2x2")
+	inputActivation := flags.String("input-activation", "identity", "activation of the input layer")
+	activation := flags.String("activation", "logistic", "activation of the hidden and output layers")
+	inhibitory := flags.Float64("inhibitory", 0.0, "density of inhibitory neurons in each layer")
+	initName := flags.String("init", "", "weight initializer of the hidden and output layers: uniform, normal, xavier, he, lecun, orthogonal, constant or zero")
+	l1 := flags.Float64("l1", 0.0, "L1 weight decay of the hidden and output layers")
+	l2 := flags.Float64("l2", 0.0, "L2 weight decay of the hidden and output layers")
+	maxNorm := flags.Float64("max-norm", 0.0, "maximum norm of each neuron's incoming weights in the hidden and output layers, 0 for none")
+	dropout := flags.Float64("dropout", 0.0, "fraction of the hidden neurons to drop from each training run")
+	dropConnect := flags.Float64("drop-connect", 0.0, "fraction of the connections into the hidden and output layers to drop from each training run")
+	data := flags.String("data", "", "JSON dataset of input configurations to train on")
+	validationPath := flags.String("validation", "", "JSON dataset to validate on instead of splitting -data")
+	validationSplit := flags.Float64("validation-split", 0.0, "fraction of -data to hold out for validation")
+	validationInterval := flags.Int("validation-interval", 1, "iterations between validation evaluations")
+	patience := flags.Int("patience", 0, "evaluations without improvement before stopping early, enabling early stopping along with -min-delta")
+	minDelta := flags.Float64("min-delta", 0.0, "drop in validation loss that counts as an improvement")
+	out := flags.String("out", "", "path to write the trained model to, .json for JSON")
+	engineName := flags.String("engine", "gradient", "training engine, gradient or default")
+	iterations := flags.Int("iterations", 10000, "number of training iterations, or epochs with -sampling epoch")
+	batch := flags.Int("batch", 1, "number of inputs per update, -1 for the full dataset")
+	sampling := flags.String("sampling", "weighted", "input sampling, weighted or epoch")
+	rate := flags.Float64("rate", ann.DefaultGradientLearningRate, "learning rate of the training engine, 0.1 by default for the default engine")
+	lossName := flags.String("loss", "", "loss to train against: mean_squared, mean_absolute, huber, binary_cross_entropy, categorical_cross_entropy, negative_log_likelihood or hinge (default to suit the output layer)")
+	scheduleName := flags.String("schedule", "", "learning rate schedule: constant, step, exponential, cosine, warmup or reduce_on_plateau")
+	warmup := flags.Int("warmup", 0, "iterations to linearly warm the learning rate up over")
+	clipValue := flags.Float64("clip-value", 0.0, "clip every gradient to this magnitude, 0 for no clipping")
+	clipNorm := flags.Float64("clip-norm", 0.0, "scale the gradients of each update down to this L2 norm, 0 for no clipping")
+	rollback := flags.Bool("rollback", false, "roll back and save the last finite weights if training diverges")
+	optimizerName := flags.String("optimizer", "sgd", "optimizer of the gradient engine: sgd, momentum, nesterov, adagrad, rmsprop or adam")
+	resume := flags.String("resume", "", "checkpoint to resume training from instead of building a new network")
+	checkpointPath := flags.String("checkpoint", "", "path to also write a checkpoint with the optimizer state to")
+	historyPath := flags.String("history", "", "path to write the training history to, .json for JSON and CSV otherwise")
+	float32Weights := flags.Bool("float32", false, "store binary model weights as float32")
+	seed := flags.Int64("seed", 0, "seed of the random source used to build and train the network, 0 for a random one")
+	debug := flags.Bool("debug", false, "log the training error as training progresses")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	sources := 0
+	for _, source := range []string{*layers, *specPath, *resume} {
+		if source != "" {
+			sources++
+		}
+	}
+
+	if sources != 1 {
+		return usageError{fmt.Errorf("exactly one of -layers, -spec or -resume is required")}
+	}
+
+	if *data == "" || *out == "" {
+		return usageError{fmt.Errorf("-data and -out are required")}
+	}
+
+	// Start from the spec if there is one, letting any flags that were given
+	// explicitly override its training settings
+	spec := &ann.NetworkSpec{Training: &ann.TrainingSpec{}}
+	if *specPath != "" {
+		var err error
+		if spec, err = loadSpec(*specPath); err != nil {
+			return err
+		}
+
+		if spec.Training == nil {
+			spec.Training = &ann.TrainingSpec{}
+		}
+	} else if *layers != "" {
+		var err error
+		spec.Layers, err = parseLayers(*layers, *inputActivation, *activation, *initName, *inhibitory)
+		if err != nil {
+			return usageError{err}
+		}
+	}
+
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if set["seed"] {
+		spec.Seed = *seed
+	}
+
+	training := spec.Training
+	if set["engine"] || *specPath == "" {
+		training.Engine = *engineName
+	}
+	// The default engine keeps its own default rate unless one is given
+	if set["rate"] || *specPath == "" && training.Engine != "default" {
+		training.LearningRate = *rate
+	}
+	if set["optimizer"] || *specPath == "" {
+		training.Optimizer = *optimizerName
+	}
+	if set["iterations"] || training.Iterations == 0 {
+		training.Iterations = *iterations
+	}
+	if set["debug"] {
+		training.Debug = *debug
+	}
+	if set["batch"] || *specPath == "" {
+		training.BatchSize = *batch
+	}
+	if set["sampling"] || *specPath == "" {
+		training.Sampling = *sampling
+	}
+	if set["loss"] || *specPath == "" {
+		training.Loss = *lossName
+	}
+	if set["schedule"] {
+		training.Schedule = *scheduleName
+		training.ScheduleParams = nil
+	}
+	if set["warmup"] {
+		training.Warmup = *warmup
+	}
+	if set["clip-value"] || set["clip-norm"] {
+		training.GradientClipping = &ann.GradientClipping{Norm: *clipNorm, Value: *clipValue}
+	}
+	if set["rollback"] {
+		training.Rollback = *rollback
+	}
+	if set["validation-split"] {
+		training.ValidationSplit = *validationSplit
+	}
+	if set["validation-interval"] {
+		training.ValidationInterval = *validationInterval
+	}
+	if set["patience"] || set["min-delta"] {
+		training.EarlyStopping = &ann.EarlyStopping{MinDelta: *minDelta, Patience: *patience}
+	}
+
+	if *dropout < 0 || *dropout >= 1 || *dropConnect < 0 || *dropConnect >= 1 {
+		return usageError{fmt.Errorf("-dropout and -drop-connect must be at least 0 and less than 1")}
+	}
+
+	if training.Iterations < 1 {
+		return usageError{fmt.Errorf("-iterations must be positive")}
+	}
+
+	var network *ann.NeuralNetwork
+	var config *ann.TrainingConfiguration
+	var err error
+	if *resume != "" {
+		network, config, err = resumeTraining(*resume, training, set["rate"])
+	} else {
+		network, config, err = spec.Build()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if *resume != "" && set["seed"] {
+		network.SetSeed(*seed)
+	}
+
+	// Regularization flags apply to every layer but the input, replacing
+	// whatever the spec or checkpoint had
+	if set["l1"] || set["l2"] || set["max-norm"] {
+		if *l1 < 0 || *l2 < 0 || *maxNorm < 0 {
+			return usageError{fmt.Errorf("-l1, -l2 and -max-norm can't be negative")}
+		}
+
+		for _, layer := range network.GetLayers()[1:] {
+			layer.Regularization = &ann.Regularization{L1: *l1, L2: *l2, MaxNorm: *maxNorm}
+		}
+	}
+
+	// Likewise for the dropout rates, where only the hidden layers have neurons
+	// to drop
+	networkLayers := network.GetLayers()
+	if set["dropout"] {
+		for i, layer := range networkLayers {
+			if i > 0 && i < len(networkLayers)-1 {
+				layer.Dropout = *dropout
+			}
+		}
+	}
+	if set["drop-connect"] {
+		for _, layer := range networkLayers[1:] {
+			layer.DropConnect = *dropConnect
+		}
+	}
+
+	if err := config.CheckEngine(); err != nil {
+		return usageError{err}
+	}
+
+	inputs, err := loadDataset(*data)
+	if err != nil {
+		return err
+	}
+
+	if err := checkDataset(inputs, network); err != nil {
+		return err
+	}
+
+	config.Inputs = inputs
+	if *validationPath != "" {
+		if config.Validation, err = loadDataset(*validationPath); err != nil {
+			return err
+		}
+
+		if err := checkDataset(config.Validation, network); err != nil {
+			return err
+		}
+	} else if err := config.SplitValidation(training.ValidationSplit); err != nil {
+		return err
+	}
+
+	// A run that was rolled back still has good weights worth saving, but the
+	// failure is reported once they are
+	history, trainErr := config.Engine.Train(training.Iterations, config)
+	if trainErr != nil && !config.Rollback {
+		return trainErr
+	}
+
+	precision := ann.PrecisionFloat64
+	if *float32Weights {
+		precision = ann.PrecisionFloat32
+	}
+
+	if err := saveModel(*out, network, precision); err != nil {
+		return err
+	}
+
+	if *historyPath != "" {
+		if err := saveHistory(*historyPath, history); err != nil {
+			return err
+		}
+	}
+
+	if *checkpointPath != "" {
+		var optimizer ann.Optimizer
+		if engine, ok := config.Engine.(*ann.GradientEngine); ok {
+			optimizer = engine.Optimizer
+		}
+
+		progress, err := config.Progress()
+		if err != nil {
+			return err
+		}
+
+		if err := saveCheckpoint(*checkpointPath, network, optimizer, progress); err != nil {
+			return err
+		}
+	}
+
+	return trainErr
+}
+
+// resumeTraining loads a checkpoint and sets up a gradient engine that picks up
+// where its optimizer, random source and schedule left off. Checkpoints without
+// an optimizer get a new one from the training spec
+func resumeTraining(path string, training *ann.TrainingSpec, overrideRate bool) (*ann.NeuralNetwork, *ann.TrainingConfiguration, error) {
+	network, optimizer, progress, err := loadCheckpoint(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if optimizer == nil {
+		if optimizer, err = training.NewOptimizer(); err != nil {
+			return nil, nil, err
+		}
+	} else if overrideRate {
+		optimizer.SetLearningRate(training.LearningRate)
+	}
+
+	loss, err := training.NewLoss()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sampling, err := ann.SamplingByName(training.Sampling)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schedule, err := training.NewSchedule()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := training.CheckGradientClipping(); err != nil {
+		return nil, nil, err
+	}
+
+	engine := ann.NewGradientEngineWithOptimizer(optimizer)
+	engine.Clipping = training.GradientClipping
+
+	config := &ann.TrainingConfiguration{
+		BatchSize:          training.BatchSize,
+		Debug:              training.Debug,
+		EarlyStopping:      training.EarlyStopping,
+		Engine:             engine,
+		Loss:               loss,
+		Network:            network,
+		Rollback:           training.Rollback,
+		Sampling:           sampling,
+		Schedule:           schedule,
+		ValidationInterval: training.ValidationInterval,
+	}
+
+	if err := config.Resume(progress); err != nil {
+		return nil, nil, err
+	}
+
+	return network, config, nil
+}
+
+// loadSpec reads a JSON network spec file
+func loadSpec(path string) (*ann.NetworkSpec, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	spec, err := ann.LoadNetworkSpec(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading spec %s: %v", path, err)
+	}
+
+	return spec, nil
+}
+
+// parseLayers parses a comma separated list of WIDTHxHEIGHT layer shapes into
+// layer specs
+func parseLayers(shapes, inputActivation, activation, init string, inhibitory float64) ([]*ann.LayerSpec, error) {
+	fields := strings.Split(shapes, ",")
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("a network needs at least an input and an output layer")
+	}
+
+	layers := make([]*ann.LayerSpec, len(fields))
+	for i, shape := range fields {
+		dims := strings.Split(strings.TrimSpace(shape), "x")
+		if len(dims) != 2 {
+			return nil, fmt.Errorf("invalid layer shape %q, expected WIDTHxHEIGHT", shape)
+		}
+
+		width, err := strconv.Atoi(dims[0])
+		if err != nil || width < 1 {
+			return nil, fmt.Errorf("invalid layer width in %q", shape)
+		}
+
+		height, err := strconv.Atoi(dims[1])
+		if err != nil || height < 1 {
+			return nil, fmt.Errorf("invalid layer height in %q", shape)
+		}
+
+		name := activation
+		if i == 0 {
+			name = inputActivation
+		}
+
+		if _, err := ann.ActivationByName(name); err != nil {
+			return nil, fmt.Errorf("%v %q", err, name)
+		}
+
+		layers[i] = &ann.LayerSpec{
+			Width:             width,
+			Height:            height,
+			Activation:        name,
+			InhibitoryDensity: inhibitory,
+		}
+
+		if init != "" && i > 0 {
+			if _, err := ann.InitializerByName(init); err != nil {
+				return nil, fmt.Errorf("%v %q", err, init)
+			}
+			layers[i].Init = &ann.InitSpec{Scheme: init}
+		}
+	}
+
+	return layers, nil
+}
+
+// checkDataset makes sure every input and expected grid in the dataset fits
+// the network's input and output layers
+func checkDataset(inputs []*ann.InputConfiguration, network *ann.NeuralNetwork) error {
+	for i, input := range inputs {
+		if !fitsLayer(input.Values, network.GetInput()) {
+			return fmt.Errorf("input %d: values do not match the %dx%d input layer",
+				i, network.GetInput().Width(), network.GetInput().Height())
+		}
+
+		if !fitsLayer(input.Expected, network.GetOutput()) {
+			return fmt.Errorf("input %d: expected values do not match the %dx%d output layer",
+				i, network.GetOutput().Width(), network.GetOutput().Height())
+		}
+	}
+
+	return nil
+}
+
+// fitsLayer reports whether the grid has exactly the shape of the layer
+func fitsLayer(grid [][]float64, layer *ann.NetworkLayer) bool {
+	if len(grid) != layer.Width() {
+		return false
+	}
+
+	for _, row := range grid {
+		if len(row) != layer.Height() {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,304 @@
+package ann
+
+import "errors"
+
+var (
+	// ErrNotCompilable is the error for when a network has connections that
+	// can't be packed into dense layers, like connections that skip a layer or
+	// repeat a pair of neurons
+	ErrNotCompilable = errors.New("Network can't be compiled into dense layers")
+)
+
+// CompiledNetwork is a dense form of a network for fast inference. Instead of
+// firing connections between neurons one at a time, each layer keeps its
+// incoming weights in a contiguous matrix and is evaluated with a single
+// matrix-vector product, producing the same outputs as the network's Run
+type CompiledNetwork struct {
+	Layers []*CompiledLayer
+	Seed   int64
+}
+
+// CompiledLayer is a single layer of a CompiledNetwork. Neurons are numbered
+// row by row, so the neuron at row r and column c of the layer is neuron
+// r*Height+c
+type CompiledLayer struct {
+	Width  int
+	Height int
+
+	// Activation is the layer's activation, while Activations holds the one
+	// each neuron actually uses, nil for binary neurons
+	Activation  Activation
+	Activations []Activation
+
+	Biases []float64
+	Types  []int
+
+	// Weights is the matrix of connection intensities from the previous layer,
+	// with a row for each neuron of this layer and a column for each neuron of
+	// the previous one. Connected marks which entries are actual connections,
+	// and is nil when the layers are fully connected
+	Weights   []float64
+	Connected []bool
+
+	Dropout        float64
+	DropConnect    float64
+	Regularization *Regularization
+	Softmax        *Softmax
+
+	// potentials and signals hold the state of the last run
+	potentials []float64
+	signals    []float64
+}
+
+// Compile packs the network into a CompiledNetwork. Connection counts and
+// inhibitory sources are folded into the weights
+func Compile(network NetworkConfiguration) (*CompiledNetwork, error) {
+	layers := network.GetLayers()
+	if len(layers) == 0 {
+		return nil, ErrNotCompilable
+	}
+
+	compiled := &CompiledNetwork{
+		Layers: make([]*CompiledLayer, len(layers)),
+		Seed:   network.GetSeed(),
+	}
+
+	var index map[*Neuron]int
+	for i, layer := range layers {
+		dst := newCompiledLayer(layer)
+		if i > 0 {
+			if err := dst.compileWeights(layer, index); err != nil {
+				return nil, err
+			}
+		}
+		compiled.Layers[i] = dst
+
+		// Number the neurons so the next layer can find the column of each of
+		// its sources
+		index = make(map[*Neuron]int, len(dst.Biases))
+		layer.EachNeuron(func(n *Neuron) {
+			index[n] = len(index)
+		})
+	}
+
+	return compiled, nil
+}
+
+// newCompiledLayer copies everything but the weights of the layer
+func newCompiledLayer(layer *NetworkLayer) *CompiledLayer {
+	dst := &CompiledLayer{
+		Width:          layer.Width(),
+		Activation:     layer.Activation,
+		Dropout:        layer.Dropout,
+		DropConnect:    layer.DropConnect,
+		Regularization: layer.Regularization,
+		Softmax:        layer.Softmax,
+	}
+	if dst.Width > 0 {
+		dst.Height = layer.Height()
+	}
+
+	layer.EachNeuron(func(n *Neuron) {
+		dst.Activations = append(dst.Activations, n.Activation)
+		dst.Biases = append(dst.Biases, n.Bias)
+		dst.Types = append(dst.Types, n.Type)
+	})
+
+	dst.potentials = make([]float64, len(dst.Biases))
+	dst.signals = make([]float64, len(dst.Biases))
+
+	return dst
+}
+
+// compileWeights fills in the weight matrix from the incoming connections of
+// the layer, given the column of each neuron in the previous layer
+func (l *CompiledLayer) compileWeights(layer *NetworkLayer, index map[*Neuron]int) error {
+	columns := len(index)
+	l.Weights = make([]float64, len(l.Biases)*columns)
+	l.Connected = make([]bool, len(l.Weights))
+
+	full := true
+	row := 0
+	for _, neurons := range layer.Neurons {
+		for _, n := range neurons {
+			for _, conn := range n.In {
+				column, ok := index[conn.Source]
+				if !ok || l.Connected[row*columns+column] {
+					return ErrNotCompilable
+				}
+
+				l.Connected[row*columns+column] = true
+				l.Weights[row*columns+column] = conn.CalculateIntensity()
+			}
+
+			full = full && len(n.In) == columns
+			row++
+		}
+	}
+
+	if full {
+		l.Connected = nil
+	}
+
+	return nil
+}
+
+// Decompile rebuilds a NeuralNetwork from the compiled network. Every
+// connection is rebuilt with a single connection count and the weight that
+// gives it the same intensity, so the rebuilt network runs the same way
+func (c *CompiledNetwork) Decompile() *NeuralNetwork {
+	network := NewNeuralNetworkWithSeed(0, 0, 0, c.Seed)
+
+	var prev []*Neuron
+	for _, src := range c.Layers {
+		layer := &NetworkLayer{
+			Activation:     src.Activation,
+			Neurons:        make([][]*Neuron, src.Width),
+			Dropout:        src.Dropout,
+			DropConnect:    src.DropConnect,
+			Regularization: src.Regularization,
+			Softmax:        src.Softmax,
+		}
+
+		neurons := make([]*Neuron, 0, len(src.Biases))
+		for row := range layer.Neurons {
+			layer.Neurons[row] = make([]*Neuron, src.Height)
+			for column := range layer.Neurons[row] {
+				i := len(neurons)
+				n := NewNeuron(src.Types[i])
+				n.Activation = src.Activations[i]
+				n.Bias = src.Biases[i]
+				layer.Neurons[row][column] = n
+				neurons = append(neurons, n)
+			}
+		}
+
+		if src.Weights != nil {
+			for i, target := range neurons {
+				for j, source := range prev {
+					if !src.connected(i*len(prev) + j) {
+						continue
+					}
+
+					conn := source.Connect(target)
+					conn.Connections = 1
+					conn.Weight = src.Weights[i*len(prev)+j]
+					if source.Type != TypeExcitatory {
+						conn.Weight = -conn.Weight
+					}
+				}
+			}
+		}
+
+		network.Layers = append(network.Layers, layer)
+		prev = neurons
+	}
+
+	return network
+}
+
+// connected reports whether the given entry of the weight matrix is an actual
+// connection
+func (l *CompiledLayer) connected(i int) bool {
+	return l.Connected == nil || l.Connected[i]
+}
+
+// Run runs the inputs through the compiled network. The outputs are available
+// from Output afterwards
+func (c *CompiledNetwork) Run(inputs [][]float64) error {
+	input := c.Layers[0]
+	if len(inputs) != input.Width {
+		return ErrArraySizeMismatch
+	}
+
+	// Layers that weren't compiled from a network still need their buffers
+	for _, layer := range c.Layers {
+		if len(layer.potentials) != len(layer.Biases) {
+			layer.potentials = make([]float64, len(layer.Biases))
+			layer.signals = make([]float64, len(layer.Biases))
+		}
+	}
+
+	for row, values := range inputs {
+		if len(values) != input.Height {
+			return ErrArraySizeMismatch
+		}
+		copy(input.potentials[row*input.Height:], values)
+	}
+
+	for i, layer := range c.Layers {
+		if i > 0 {
+			layer.propagate(c.Layers[i-1].signals)
+		}
+
+		if err := layer.activate(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// propagate sets the potential of every neuron of the layer to its bias plus
+// the weighted signals of the previous layer, adding them up in the same order
+// the network's connections would fire
+func (l *CompiledLayer) propagate(signals []float64) {
+	columns := len(signals)
+	for i := range l.potentials {
+		potential := l.Biases[i]
+		for j, weight := range l.Weights[i*columns : (i+1)*columns] {
+			potential += weight * signals[j]
+		}
+		l.potentials[i] = potential
+	}
+}
+
+// activate applies the activations and softmax of the layer, and works out the
+// signal each neuron sends on to the next layer
+func (l *CompiledLayer) activate(index int) error {
+	for i, activation := range l.Activations {
+		if activation != nil {
+			l.potentials[i] = activation.Activate(l.potentials[i])
+		}
+	}
+
+	if l.Softmax != nil {
+		if l.Softmax.PerRow {
+			for row := 0; row < l.Width; row++ {
+				l.Softmax.normalize(l.potentials[row*l.Height : (row+1)*l.Height])
+			}
+		} else {
+			l.Softmax.normalize(l.potentials)
+		}
+	}
+
+	for i, potential := range l.potentials {
+		if !isFinite(potential) {
+			return &NumericError{Column: i % l.Height, Layer: index, Quantity: "potential", Row: i / l.Height, Value: potential}
+		}
+
+		// Binary neurons only send a signal once they cross the threshold
+		switch {
+		case l.Activations[i] != nil:
+			l.signals[i] = potential
+		case potential >= PotentialThreshold:
+			l.signals[i] = 1.0
+		default:
+			l.signals[i] = 0.0
+		}
+	}
+
+	return nil
+}
+
+// Output returns the potentials of the output layer from the last run
+func (c *CompiledNetwork) Output() [][]float64 {
+	layer := c.Layers[len(c.Layers)-1]
+	output := make([][]float64, layer.Width)
+	for row := range output {
+		output[row] = make([]float64, layer.Height)
+		copy(output[row], layer.potentials[row*layer.Height:])
+	}
+
+	return output
+}
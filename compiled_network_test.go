@@ -0,0 +1,159 @@
+package ann
+
+import (
+	"math"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestCompiledNetwork(suite *testing.T) {
+	newNetwork := func() *NeuralNetwork {
+		network := newTestNetwork(7,
+			LayerConfiguration{Width: 3, Height: 4, Activation: &Identity{}},
+			LayerConfiguration{
+				Width:             4,
+				Height:            4,
+				Activation:        &Tanh{},
+				Connectivity:      ConnectLocal,
+				Radius:            1,
+				InhibitoryDensity: 0.3,
+				Initializer:       NewUniformInitializer(-1, 1),
+			},
+			LayerConfiguration{
+				Width:             3,
+				Height:            3,
+				Activation:        &LeakyReLU{Alpha: 0.1},
+				InhibitoryDensity: 0.3,
+				Initializer:       NewUniformInitializer(-1, 1),
+			},
+			LayerConfiguration{
+				Width:       2,
+				Height:      3,
+				Activation:  &Identity{},
+				Initializer: NewUniformInitializer(-1, 1),
+				Softmax:     &Softmax{PerRow: true},
+			})
+		randomizeBiases(network)
+		network.Layers[2].Neurons[1][2].In[3].Strengthen()
+
+		return network
+	}
+
+	newInputs := func(network *NeuralNetwork, count int) [][][]float64 {
+		inputs := make([][][]float64, count)
+		for i := range inputs {
+			inputs[i] = newTestInput(network, 2)
+		}
+
+		return inputs
+	}
+
+	outputOf := func(network *NeuralNetwork) [][]float64 {
+		output := make([][]float64, network.GetOutput().Width())
+		network.GetOutput().EachNeuronWithIndex(func(n *Neuron, row, column int) {
+			output[row] = append(output[row], n.Potential)
+		})
+
+		return output
+	}
+
+	this.Should("Produce exactly the same outputs as running the network", suite,
+		func() {
+			network := newNetwork()
+			compiled, err := Compile(network)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(compiled.Layers).To(HaveLen(4))
+			Expect(compiled.Layers[0].Weights).To(BeNil())
+			Expect(compiled.Layers[1].Weights).To(HaveLen(16 * 12))
+			Expect(compiled.Layers[1].Connected).ToNot(BeNil())
+			Expect(compiled.Layers[2].Connected).To(BeNil())
+
+			for _, input := range newInputs(network, 20) {
+				Expect(network.Run(input)).To(Succeed())
+				Expect(compiled.Run(input)).To(Succeed())
+				Expect(compiled.Output()).To(Equal(outputOf(network)))
+			}
+		})
+
+	this.Should("Match networks of binary neurons", suite,
+		func() {
+			network := newTestNetwork(5,
+				LayerConfiguration{Width: 3, Height: 4},
+				LayerConfiguration{Width: 3, Height: 3, InhibitoryDensity: 0.5,
+					Initializer: NewUniformInitializer(-1, 1)},
+				LayerConfiguration{Width: 2, Height: 2, Initializer: NewUniformInitializer(-1, 1)})
+
+			compiled, err := Compile(network)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, input := range newInputs(network, 20) {
+				Expect(network.Run(input)).To(Succeed())
+				Expect(compiled.Run(input)).To(Succeed())
+				Expect(compiled.Output()).To(Equal(outputOf(network)))
+			}
+		})
+
+	this.Should("Convert back to a network that runs the same way", suite,
+		func() {
+			network := newNetwork()
+			compiled, err := Compile(network)
+			Expect(err).ToNot(HaveOccurred())
+
+			rebuilt := compiled.Decompile()
+			Expect(rebuilt.GetSeed()).To(Equal(int64(7)))
+			Expect(rebuilt.GetOutput().Softmax).To(Equal(&Softmax{PerRow: true}))
+			Expect(rebuilt.Layers[1].Neurons[2][2].In).To(HaveLen(len(network.Layers[1].Neurons[2][2].In)))
+
+			for _, input := range newInputs(network, 10) {
+				Expect(network.Run(input)).To(Succeed())
+				Expect(rebuilt.Run(input)).To(Succeed())
+				Expect(outputOf(rebuilt)).To(Equal(outputOf(network)))
+			}
+
+			recompiled, err := Compile(rebuilt)
+			Expect(err).ToNot(HaveOccurred())
+			for i, layer := range recompiled.Layers {
+				Expect(layer.Weights).To(Equal(compiled.Layers[i].Weights))
+				Expect(layer.Connected).To(Equal(compiled.Layers[i].Connected))
+				Expect(layer.Biases).To(Equal(compiled.Layers[i].Biases))
+				Expect(layer.Types).To(Equal(compiled.Layers[i].Types))
+			}
+		})
+
+	this.Should("Refuse networks that don't fit into dense layers", suite,
+		func() {
+			_, err := Compile(NewNeuralNetworkWithSeed(0, 0, 0, 1))
+			Expect(err).To(Equal(ErrNotCompilable))
+
+			network := newNetwork()
+			network.Layers[0].Neurons[0][0].Connect(network.GetOutput().Neurons[0][0])
+			_, err = Compile(network)
+			Expect(err).To(Equal(ErrNotCompilable))
+
+			network = newNetwork()
+			network.Layers[2].Neurons[0][0].Connect(network.GetOutput().Neurons[0][0])
+			_, err = Compile(network)
+			Expect(err).To(Equal(ErrNotCompilable))
+		})
+
+	this.Should("Report bad inputs and potentials that aren't finite", suite,
+		func() {
+			network := newNetwork()
+			compiled, err := Compile(network)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(compiled.Run([][]float64{[]float64{1, 2, 3, 4}})).To(Equal(ErrArraySizeMismatch))
+			Expect(compiled.Run([][]float64{
+				[]float64{1, 2, 3, 4},
+				[]float64{1, 2, 3},
+				[]float64{1, 2, 3, 4},
+			})).To(Equal(ErrArraySizeMismatch))
+
+			compiled.Layers[2].Biases[5] = math.Inf(1)
+			Expect(compiled.Run(newInputs(network, 1)[0])).To(Equal(&NumericError{
+				Column: 2, Layer: 2, Quantity: "potential", Row: 1, Value: math.Inf(1)}))
+		})
+}
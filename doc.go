@@ -0,0 +1,4 @@
+// Package ann contains the core types for building, running and training
+// layered artificial neural networks. The ann command in cmd/ann is a thin
+// binary on top of this package
+package ann
@@ -1,12 +1,41 @@
-package main
+package ann
 
 import "math"
 
 // Evaluator is the default evaluator to be used across the neural network
 var Evaluator = &DefaultEvaluator{}
 
+const (
+	// DefaultEvaluatorLearningRate is the step size of the default evaluator's
+	// adjustments when it isn't given one
+	DefaultEvaluatorLearningRate = 0.1
+)
+
 // DefaultEvaluator is the default evaluator for our neural networks
-type DefaultEvaluator struct{}
+type DefaultEvaluator struct {
+	// LearningRate scales every adjustment, defaulting to
+	// DefaultEvaluatorLearningRate when zero
+	LearningRate float64 `json:"learning_rate"`
+}
+
+// GetLearningRate returns the step size of the evaluator's adjustments
+func (e *DefaultEvaluator) GetLearningRate() float64 {
+	if e.LearningRate == 0 {
+		return DefaultEvaluatorLearningRate
+	}
+
+	return e.LearningRate
+}
+
+// SetLearningRate sets the step size of the evaluator's adjustments
+func (e *DefaultEvaluator) SetLearningRate(rate float64) {
+	e.LearningRate = rate
+}
+
+// Run processes the network with the given input
+func (e *DefaultEvaluator) Run(input [][]float64, network NetworkConfiguration) error {
+	return network.Run(input)
+}
 
 // AdjustLayer performs the actual fine tuning of the current layer given a base
 // error mapping. This returns the error mapping for the current layer
@@ -35,7 +64,7 @@ func (e *DefaultEvaluator) AdjustLayer(layer *NetworkLayer, errMap map[*Neuron]*
 			// and taking our current weight into account alongside the sigmoid helps.
 			// Right now we've got the scaling value cranked all the way up because it
 			// makes things converge more rapidly
-			adjStep := 0.1 * proportionalWeight * err.Sigmoid()
+			adjStep := e.GetLearningRate() * proportionalWeight * err.Sigmoid()
 			// adjStep := 0.2 * conn.Weight * proportionalWeight * err.Sigmoid()
 
 			// Keep track of how much error we have at this layer so we can percolate
@@ -59,12 +88,29 @@ func (e *DefaultEvaluator) AdjustLayer(layer *NetworkLayer, errMap map[*Neuron]*
 	return currErrMap, nil
 }
 
+// AdjustBiases nudges the bias of each neuron in the layer towards its expected
+// value. The bias is treated like one more incoming connection, so it takes an
+// equal share of the neuron's error
+func (e *DefaultEvaluator) AdjustBiases(layer *NetworkLayer, errMap map[*Neuron]*NeuronError) {
+	layer.EachNeuron(func(n *Neuron) {
+		err := errMap[n]
+		adjStep := e.GetLearningRate() * err.Sigmoid() / float64(len(n.In)+1)
+		n.Bias += adjStep * float64(err.Direction)
+	})
+}
+
 // PerformBackPropagation performs traditional back propagation of the network signal
 func (e *DefaultEvaluator) PerformBackPropagation(expected [][]float64, network NetworkConfiguration) error {
-	baseError, err := e.CalculateError(expected, network)
+	return e.PerformBackPropagationWithLoss(expected, network, &MeanSquaredLoss{})
+}
+
+// PerformBackPropagationWithLoss performs back propagation of the network
+// signal, measuring the output error with the given loss
+func (e *DefaultEvaluator) PerformBackPropagationWithLoss(expected [][]float64, network NetworkConfiguration, loss Loss) error {
+	baseError, err := e.CalculateErrorWithLoss(expected, network, loss)
 
 	if err != nil {
-		Error.Println("Error while attempting to backpropagate:", err)
+		errorLog.Println("Error while attempting to backpropagate:", err)
 		return err
 	}
 
@@ -73,21 +119,34 @@ func (e *DefaultEvaluator) PerformBackPropagation(expected [][]float64, network
 	// No point trying to adjust the outgoing weights on the last layer amirite
 	for i := len(layers) - 2; i >= 0; i-- {
 		layer := layers[i]
+		e.AdjustBiases(layers[i+1], baseError)
 		baseError, err = e.AdjustLayer(layer, baseError)
 
 		// Make sure we capture any failures in the layers
 		if err != nil {
-			Error.Println("Error while attempting to backpropagate:", err)
+			errorLog.Println("Error while attempting to backpropagate:", err)
 			return err
 		}
 	}
 
+	if err := CheckWeights(network); err != nil {
+		errorLog.Println("Error while attempting to backpropagate:", err)
+		return err
+	}
+
 	return nil
 }
 
 // CalculateError calculates the error of the output layer versuses the provided
 // set of expected values
 func (e *DefaultEvaluator) CalculateError(expected [][]float64, network NetworkConfiguration) (map[*Neuron]*NeuronError, error) {
+	return e.CalculateErrorWithLoss(expected, network, &MeanSquaredLoss{})
+}
+
+// CalculateErrorWithLoss calculates the error of the output layer versus the
+// provided set of expected values using the given loss. The direction of each
+// error is the way the output has to move to reduce the loss
+func (e *DefaultEvaluator) CalculateErrorWithLoss(expected [][]float64, network NetworkConfiguration, loss Loss) (map[*Neuron]*NeuronError, error) {
 	layer := network.GetOutput()
 
 	if len(expected) != len(layer.Neurons) ||
@@ -106,13 +165,13 @@ func (e *DefaultEvaluator) CalculateError(expected [][]float64, network NetworkC
 			}
 
 			direction := 1
-			if val < neuron.Potential {
+			if loss.Gradient(val, neuron.Potential) > 0 {
 				direction = -1
 			}
 
 			err := &NeuronError{
 				Direction:   direction,
-				Error:       e.MeanSquaredError(val, neuron.Potential),
+				Error:       loss.Value(val, neuron.Potential),
 				TotalWeight: weight,
 			}
 			errMap[neuron] = err
@@ -122,37 +181,98 @@ func (e *DefaultEvaluator) CalculateError(expected [][]float64, network NetworkC
 	return errMap, nil
 }
 
-// Train runs the given network with
-func (e *DefaultEvaluator) Train(iterations int, config *TrainingConfiguration) {
+// Train runs the given network with the configured inputs for the specified
+// number of iterations. The default evaluator adjusts the network after every
+// input, so batches only decide which inputs are visited and in what order.
+// Training stops sooner if the configuration's early stopping runs out of
+// patience, and the configuration's Schedule sets the learning rate of each
+// iteration. Training stops with the first error, such as a NumericError once a
+// value stops being finite, rolling back the failed iteration if the
+// configuration's Rollback is set. Settings the evaluator can't train with are
+// rejected up front, whatever the configuration's Engine is, see CheckEngine.
+// The history of the run is returned
+func (e *DefaultEvaluator) Train(iterations int, config *TrainingConfiguration) (*History, error) {
 	// func (e *DefaultEvaluator) Train(iterations int, input, expected [][]float64, network NetworkConfiguration) {
 	network := config.Network
+	loss := config.GetLoss()
 
 	// Log 100 frames if we're debugging
 	debugLogTick := iterations / 100
+	if debugLogTick == 0 {
+		debugLogTick = 1
+	}
+
+	if config.Debug {
+		debugLog.Printf("Training with seed %d\n", network.GetSeed())
+	}
+
+	initial := e.GetLearningRate()
+	defer e.SetLearningRate(initial)
+
+	history := newHistory(config)
+	if err := config.checkDefaultEvaluator(); err != nil {
+		return history, err
+	}
+
+	if len(config.Inputs) == 0 {
+		return history, ErrNoInputs
+	}
+
+	validation := config.newValidationTracker()
+	defer validation.finish()
+	rollback := config.newRollback(nil)
+
 	for i := 0; i < iterations; i++ {
-		// If we're debugging, log every 1/100th of the set as well as the final
-		// state
-		if config.Debug && (i%debugLogTick == 0 || i == iterations-1) {
-			network.SetDebug(true)
-		} else {
-			network.SetDebug(false)
+		rollback.save()
+		inputs := make([]*InputConfiguration, 0)
+		for _, batch := range config.Batches() {
+			inputs = append(inputs, batch...)
 		}
-		input := config.PickInput()
-		network.Run(input.Values)
-		e.PerformBackPropagation(input.Expected, network)
-
-		if network.GetDebug() {
-			totalError := 0.0
-			for i, row := range input.Expected {
-				for j, val := range row {
-					totalError += math.Abs(
-						Evaluator.LinearError(val, network.GetOutput().Neurons[i][j].Potential))
-				}
+
+		e.SetLearningRate(config.scheduledRate(initial, config.Iteration))
+		record := &HistoryRecord{Iteration: config.Iteration, LearningRate: e.GetLearningRate()}
+
+		for j, input := range inputs {
+			// If we're debugging, log every 1/100th of the set as well as the final
+			// state
+			if config.Debug && j == len(inputs)-1 && (i%debugLogTick == 0 || i == iterations-1) {
+				network.SetDebug(true)
+			} else {
+				network.SetDebug(false)
 			}
+			if err := network.Run(input.Values); err != nil {
+				errorLog.Println("Error while attempting to train:", err)
+				return history, rollback.fail(err)
+			}
+
+			totalError := LayerLoss(loss, input.Expected, network.GetOutput())
+			record.TrainingLoss += totalError / float64(len(inputs))
+			if err := e.PerformBackPropagationWithLoss(input.Expected, network, loss); err != nil {
+				return history, rollback.fail(err)
+			}
+
+			if network.GetDebug() {
+				debugLog.Printf("Total error: %.3f\n", totalError)
+			}
+		}
 
-			Debug.Printf("Total error: %.3f\n", totalError)
+		eval, stop, err := validation.step(config.Iteration)
+		if err != nil {
+			errorLog.Println("Error while attempting to validate:", err)
+			return history, rollback.fail(err)
+		}
+
+		record.Validation = eval
+		config.observe(record.Validation)
+		history.add(record)
+		config.Iteration++
+		if stop {
+			history.StoppedEarly = true
+			return history, nil
 		}
 	}
+
+	return history, nil
 }
 
 // LinearError calculates the linear error between two float values
@@ -0,0 +1,251 @@
+package ann
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var (
+	// ErrInvalidNetwork is the error for when a saved network refers to layers,
+	// neurons or connections that don't exist
+	ErrInvalidNetwork = errors.New("Invalid saved network representation")
+)
+
+// flatNetwork is the flat, cycle free representation of a network shared by
+// the saved network formats. Neurons are addressed by layer, row and column,
+// and connections by their index
+type flatNetwork struct {
+	Version            int              `json:"version"`
+	CurrentTimeStep    float64          `json:"current_time_step"`
+	PotentialStep      float64          `json:"potential_step"`
+	PotentialThreshold float64          `json:"potential_threshold"`
+	TimeStepSize       float64          `json:"time_step_size"`
+	Seed               int64            `json:"seed"`
+	Layers             []flatLayer      `json:"layers"`
+	Connections        []flatConnection `json:"connections"`
+}
+
+type flatLayer struct {
+	Width          int             `json:"width"`
+	Height         int             `json:"height"`
+	Activation     *flatActivation `json:"activation,omitempty"`
+	Dropout        float64         `json:"dropout,omitempty"`
+	DropConnect    float64         `json:"drop_connect,omitempty"`
+	Regularization *Regularization `json:"regularization,omitempty"`
+	Softmax        *Softmax        `json:"softmax,omitempty"`
+	Neurons        [][]flatNeuron  `json:"neurons"`
+}
+
+type flatActivation struct {
+	Name   string          `json:"name"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type flatNeuron struct {
+	Bias float64 `json:"bias"`
+	Type int     `json:"type"`
+
+	// Incoming lists the indices of the neuron's incoming connections in the
+	// order they were attached
+	Incoming []int `json:"incoming,omitempty"`
+}
+
+type flatConnection struct {
+	Source      [3]int  `json:"source"`
+	Target      [3]int  `json:"target"`
+	Connections int     `json:"connections"`
+	Weight      float64 `json:"weight"`
+}
+
+// flatten converts the network into its flat representation
+func (n *NeuralNetwork) flatten() (*flatNetwork, error) {
+	src := &flatNetwork{
+		CurrentTimeStep:    n.CurrentTimeStep,
+		PotentialStep:      n.PotentialStep,
+		PotentialThreshold: n.PotentialThreshold,
+		TimeStepSize:       n.TimeStepSize,
+		Seed:               n.Seed,
+		Layers:             make([]flatLayer, len(n.Layers)),
+		Connections:        make([]flatConnection, 0),
+	}
+
+	// Track where every neuron and connection lives so they can be referenced
+	// by index instead of by pointer
+	positions := make(map[*Neuron][3]int)
+	n.eachNeuronWithPosition(func(neuron *Neuron, pos [3]int) {
+		positions[neuron] = pos
+	})
+
+	connIndex := make(map[*NeuronConnection]int)
+	for i, layer := range n.Layers {
+		dst := flatLayer{
+			Width:          layer.Width(),
+			Dropout:        layer.Dropout,
+			DropConnect:    layer.DropConnect,
+			Regularization: layer.Regularization,
+			Softmax:        layer.Softmax,
+			Neurons:        make([][]flatNeuron, layer.Width()),
+		}
+		if dst.Width > 0 {
+			dst.Height = layer.Height()
+		}
+
+		if layer.Activation != nil {
+			params, err := json.Marshal(layer.Activation)
+			if err != nil {
+				return nil, err
+			}
+
+			dst.Activation = &flatActivation{Name: layer.Activation.Name(), Params: params}
+		}
+
+		layer.EachNeuronWithIndex(func(neuron *Neuron, row, column int) {
+			if dst.Neurons[row] == nil {
+				dst.Neurons[row] = make([]flatNeuron, dst.Height)
+			}
+			dst.Neurons[row][column] = flatNeuron{Bias: neuron.Bias, Type: neuron.Type}
+
+			// Walk the outgoing connections in order so the firing order is
+			// preserved when the network is rebuilt
+			for _, conn := range neuron.Out {
+				connIndex[conn] = len(src.Connections)
+				src.Connections = append(src.Connections, flatConnection{
+					Source:      [3]int{i, row, column},
+					Target:      positions[conn.Target],
+					Connections: conn.Connections,
+					Weight:      conn.Weight,
+				})
+			}
+		})
+		src.Layers[i] = dst
+	}
+
+	n.eachNeuronWithPosition(func(neuron *Neuron, pos [3]int) {
+		dst := &src.Layers[pos[0]].Neurons[pos[1]][pos[2]]
+		for _, conn := range neuron.In {
+			dst.Incoming = append(dst.Incoming, connIndex[conn])
+		}
+	})
+
+	return src, nil
+}
+
+// unflatten replaces the network with the one described by the flat
+// representation
+func (n *NeuralNetwork) unflatten(src *flatNetwork) error {
+	layers := make([]*NetworkLayer, len(src.Layers))
+	for i, srcLayer := range src.Layers {
+		if len(srcLayer.Neurons) != srcLayer.Width {
+			return ErrInvalidNetwork
+		}
+
+		layer := &NetworkLayer{
+			Neurons:        make([][]*Neuron, srcLayer.Width),
+			Dropout:        srcLayer.Dropout,
+			DropConnect:    srcLayer.DropConnect,
+			Regularization: srcLayer.Regularization,
+			Softmax:        srcLayer.Softmax,
+		}
+		for row := range layer.Neurons {
+			if len(srcLayer.Neurons[row]) != srcLayer.Height {
+				return ErrInvalidNetwork
+			}
+
+			layer.Neurons[row] = make([]*Neuron, srcLayer.Height)
+			for column, srcNeuron := range srcLayer.Neurons[row] {
+				neuron := NewNeuron(srcNeuron.Type)
+				neuron.Bias = srcNeuron.Bias
+				layer.Neurons[row][column] = neuron
+			}
+		}
+
+		if srcLayer.Activation != nil {
+			activation, err := ActivationByName(srcLayer.Activation.Name)
+			if err != nil {
+				return err
+			}
+
+			if len(srcLayer.Activation.Params) > 0 {
+				if err := json.Unmarshal(srcLayer.Activation.Params, activation); err != nil {
+					return err
+				}
+			}
+			layer.SetActivation(activation)
+		}
+
+		layers[i] = layer
+	}
+
+	lookup := func(pos [3]int) *Neuron {
+		if pos[0] < 0 || pos[0] >= len(layers) {
+			return nil
+		}
+
+		neurons := layers[pos[0]].Neurons
+		if pos[1] < 0 || pos[1] >= len(neurons) ||
+			pos[2] < 0 || pos[2] >= len(neurons[pos[1]]) {
+			return nil
+		}
+
+		return neurons[pos[1]][pos[2]]
+	}
+
+	conns := make([]*NeuronConnection, len(src.Connections))
+	for i, srcConn := range src.Connections {
+		source := lookup(srcConn.Source)
+		target := lookup(srcConn.Target)
+		if source == nil || target == nil {
+			return ErrInvalidNetwork
+		}
+
+		conn := NewNeuronConnection(source, target)
+		conn.Connections = srcConn.Connections
+		conn.Weight = srcConn.Weight
+		source.AddOutgoing(conn)
+		conns[i] = conn
+	}
+
+	// Every connection has to be attached to its target exactly once
+	attached := make([]bool, len(conns))
+	for i, srcLayer := range src.Layers {
+		for row := range srcLayer.Neurons {
+			for column, srcNeuron := range srcLayer.Neurons[row] {
+				neuron := layers[i].Neurons[row][column]
+				for _, index := range srcNeuron.Incoming {
+					if index < 0 || index >= len(conns) || attached[index] ||
+						conns[index].Target != neuron {
+						return ErrInvalidNetwork
+					}
+
+					attached[index] = true
+					neuron.AddIncoming(conns[index])
+				}
+			}
+		}
+	}
+
+	for _, ok := range attached {
+		if !ok {
+			return ErrInvalidNetwork
+		}
+	}
+
+	n.CurrentTimeStep = src.CurrentTimeStep
+	n.Layers = layers
+	n.PotentialStep = src.PotentialStep
+	n.PotentialThreshold = src.PotentialThreshold
+	n.TimeStepSize = src.TimeStepSize
+	n.SetSeed(src.Seed)
+
+	return nil
+}
+
+// eachNeuronWithPosition performs some function against every neuron in the
+// network along with its layer, row and column
+func (n *NeuralNetwork) eachNeuronWithPosition(do func(neuron *Neuron, pos [3]int)) {
+	for i, layer := range n.Layers {
+		layer.EachNeuronWithIndex(func(neuron *Neuron, row, column int) {
+			do(neuron, [3]int{i, row, column})
+		})
+	}
+}
@@ -0,0 +1,5 @@
+module github.com/connerhansen/ann
+
+go 1.16
+
+require github.com/onsi/gomega v1.10.5
@@ -0,0 +1,194 @@
+package ann
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+const (
+	// DefaultGradientCheckEpsilon is the perturbation CheckGradients uses when
+	// it isn't given one
+	DefaultGradientCheckEpsilon = 1e-6
+
+	// gradientCheckFloor keeps the relative error of gradients that are both
+	// practically zero from blowing up
+	gradientCheckFloor = 1e-8
+)
+
+// GradientCalculator is anything that calculates analytic gradients of a loss
+// for a network after running it, like the GradientEngine
+type GradientCalculator interface {
+	CalculateGradientsWithLoss(expected [][]float64, network NetworkConfiguration, loss Loss, grads *Gradients) error
+	Run(input [][]float64, network NetworkConfiguration) error
+}
+
+// GradientCheck compares analytic gradients with finite difference estimates
+// of the same gradients, layer by layer. Relative errors are
+// |analytic - numeric| / max(|analytic|, |numeric|), so errors around 1e-7 or
+// less mean the gradients are right and anything near 1 means they're wrong
+type GradientCheck struct {
+	// Layers has an entry for every layer after the input layer, which has no
+	// parameters of its own
+	Layers           []*LayerGradientCheck `json:"layers"`
+	MaxRelativeError float64               `json:"max_relative_error"`
+}
+
+// LayerGradientCheck is the gradient check of a layer's biases and incoming
+// weights
+type LayerGradientCheck struct {
+	Layer             int     `json:"layer"`
+	MaxRelativeError  float64 `json:"max_relative_error"`
+	MeanRelativeError float64 `json:"mean_relative_error"`
+	Parameters        int     `json:"parameters"`
+
+	// Worst is the parameter with the largest relative error
+	Worst *ParameterGradient `json:"worst,omitempty"`
+}
+
+// ParameterGradient is the analytic and numeric gradient of a single bias or
+// incoming weight of the neuron at Row and Column
+type ParameterGradient struct {
+	Analytic float64 `json:"analytic"`
+	Column   int     `json:"column"`
+	Numeric  float64 `json:"numeric"`
+
+	// Quantity is either "bias" or "weight", with Incoming the index of the
+	// weight's connection in the neuron's incoming connections
+	Incoming int    `json:"incoming"`
+	Quantity string `json:"quantity"`
+	Row      int    `json:"row"`
+}
+
+// RelativeError returns the relative error between the analytic and numeric
+// gradient
+func (p *ParameterGradient) RelativeError() float64 {
+	scale := math.Max(math.Max(math.Abs(p.Analytic), math.Abs(p.Numeric)), gradientCheckFloor)
+	return math.Abs(p.Analytic-p.Numeric) / scale
+}
+
+// CheckGradients compares the gradients the calculator finds for the total
+// loss over the inputs with central finite differences, nudging every bias and
+// incoming weight by epsilon either way and rerunning the network with
+// NeuralNetwork.Run. A non-positive epsilon uses DefaultGradientCheckEpsilon.
+// Binary neurons have no gradient, so check networks of activated layers. The
+// network is left with its original weights
+func CheckGradients(calculator GradientCalculator, network NetworkConfiguration, inputs []*InputConfiguration, loss Loss, epsilon float64) (*GradientCheck, error) {
+	if epsilon <= 0 {
+		epsilon = DefaultGradientCheckEpsilon
+	}
+
+	grads := NewGradients()
+	for i, input := range inputs {
+		if err := calculator.Run(input.Values, network); err != nil {
+			return nil, fmt.Errorf("Input %d: %v", i, err)
+		}
+
+		if err := calculator.CalculateGradientsWithLoss(input.Expected, network, loss, grads); err != nil {
+			return nil, fmt.Errorf("Input %d: %v", i, err)
+		}
+	}
+
+	totalLoss := func() (float64, error) {
+		total := 0.0
+		for i, input := range inputs {
+			if err := network.Run(input.Values); err != nil {
+				return 0, fmt.Errorf("Input %d: %v", i, err)
+			}
+
+			total += LayerLoss(loss, input.Expected, network.GetOutput())
+		}
+
+		return total, nil
+	}
+
+	// numeric estimates the gradient of the parameter, always putting back its
+	// original value
+	numeric := func(param *float64) (float64, error) {
+		orig := *param
+		defer func() { *param = orig }()
+
+		*param = orig + epsilon
+		plus, err := totalLoss()
+		if err != nil {
+			return 0, err
+		}
+
+		*param = orig - epsilon
+		minus, err := totalLoss()
+		if err != nil {
+			return 0, err
+		}
+
+		return (plus - minus) / (2 * epsilon), nil
+	}
+
+	check := &GradientCheck{}
+	layers := network.GetLayers()
+	for i := 1; i < len(layers); i++ {
+		result := &LayerGradientCheck{Layer: i}
+		total := 0.0
+		add := func(param *ParameterGradient, value *float64) error {
+			var err error
+			if param.Numeric, err = numeric(value); err != nil {
+				return err
+			}
+
+			relative := param.RelativeError()
+			total += relative
+			result.Parameters++
+			if result.Worst == nil || relative > result.MaxRelativeError {
+				result.MaxRelativeError = relative
+				result.Worst = param
+			}
+
+			return nil
+		}
+
+		for row, neurons := range layers[i].Neurons {
+			for column, n := range neurons {
+				param := &ParameterGradient{Analytic: grads.Biases[n], Column: column, Quantity: "bias", Row: row}
+				if err := add(param, &n.Bias); err != nil {
+					return nil, err
+				}
+
+				for j, conn := range n.In {
+					param := &ParameterGradient{Analytic: grads.Weights[conn], Column: column,
+						Incoming: j, Quantity: "weight", Row: row}
+					if err := add(param, &conn.Weight); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+
+		if result.Parameters > 0 {
+			result.MeanRelativeError = total / float64(result.Parameters)
+		}
+		check.MaxRelativeError = math.Max(check.MaxRelativeError, result.MaxRelativeError)
+		check.Layers = append(check.Layers, result)
+	}
+
+	return check, nil
+}
+
+// WriteText writes the check as a human readable table
+func (c *GradientCheck) WriteText(w io.Writer) error {
+	out := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(out, "max relative error: %.3g\n\n", c.MaxRelativeError)
+
+	fmt.Fprintln(out, "layer\tparameters\tmax\tmean\tworst")
+	for _, layer := range c.Layers {
+		worst := ""
+		if p := layer.Worst; p != nil {
+			worst = fmt.Sprintf("%s at (%d, %d): analytic %.6g, numeric %.6g",
+				p.Quantity, p.Row, p.Column, p.Analytic, p.Numeric)
+		}
+
+		fmt.Fprintf(out, "%d\t%d\t%.3g\t%.3g\t%s\n",
+			layer.Layer, layer.Parameters, layer.MaxRelativeError, layer.MeanRelativeError, worst)
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,107 @@
+package ann
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+// brokenCalculator doubles the gradients of one layer's incoming weights, the
+// kind of mistake a gradient check should catch
+type brokenCalculator struct {
+	*GradientEngine
+	layer *NetworkLayer
+}
+
+func (c *brokenCalculator) CalculateGradientsWithLoss(expected [][]float64, network NetworkConfiguration, loss Loss, grads *Gradients) error {
+	fresh := NewGradients()
+	if err := c.GradientEngine.CalculateGradientsWithLoss(expected, network, loss, fresh); err != nil {
+		return err
+	}
+
+	c.layer.EachNeuron(func(n *Neuron) {
+		for _, conn := range n.In {
+			fresh.Weights[conn] *= 2
+		}
+	})
+
+	for conn, grad := range fresh.Weights {
+		grads.Weights[conn] += grad
+	}
+	for n, grad := range fresh.Biases {
+		grads.Biases[n] += grad
+	}
+
+	return nil
+}
+
+func TestGradientCheck(suite *testing.T) {
+	newNetwork := func(activation Activation, softmax *Softmax) *NeuralNetwork {
+		return newTestNetwork(21,
+			LayerConfiguration{Width: 2, Height: 2, Activation: &Identity{}},
+			LayerConfiguration{Width: 3, Height: 3, Activation: activation,
+				InhibitoryDensity: 0.3, Initializer: &XavierInitializer{}},
+			LayerConfiguration{Width: 1, Height: 3, Activation: activation,
+				Softmax: softmax, Initializer: &XavierInitializer{}})
+	}
+
+	inputs := []*InputConfiguration{
+		&InputConfiguration{Values: [][]float64{{0.1, 0.9}, {0.4, 0.2}}, Expected: [][]float64{{0, 1, 0}}, Weight: 1},
+		&InputConfiguration{Values: [][]float64{{0.7, 0.3}, {0.5, 0.8}}, Expected: [][]float64{{1, 0, 0}}, Weight: 1},
+	}
+
+	this.Should("Pass the gradient engine's gradients", suite,
+		func() {
+			cases := []struct {
+				activation Activation
+				softmax    *Softmax
+				loss       Loss
+			}{
+				{&Tanh{}, nil, &MeanSquaredLoss{}},
+				{&Logistic{}, nil, &BinaryCrossEntropyLoss{}},
+				{&Identity{}, &Softmax{}, &CategoricalCrossEntropyLoss{}},
+				{&Softplus{}, &Softmax{Log: true}, &NegativeLogLikelihoodLoss{}},
+				{&Tanh{}, nil, NewHuberLoss(0.1)},
+			}
+
+			for _, c := range cases {
+				network := newNetwork(c.activation, c.softmax)
+				check, err := CheckGradients(NewGradientEngine(0.5), network, inputs, c.loss, 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(check.Layers).To(HaveLen(2))
+				Expect(check.Layers[0].Parameters).To(Equal(9 + 9*4))
+				Expect(check.Layers[1].Parameters).To(Equal(3 + 3*9))
+				Expect(check.MaxRelativeError).To(BeNumerically("<", 1e-5), c.loss.Name())
+			}
+		})
+
+	this.Should("Point at the layer whose gradients are wrong", suite,
+		func() {
+			network := newNetwork(&Tanh{}, nil)
+			weight := network.Layers[1].Neurons[2][1].In[3].Weight
+			calculator := &brokenCalculator{GradientEngine: NewGradientEngine(0.5), layer: network.GetOutput()}
+
+			check, err := CheckGradients(calculator, network, inputs, &MeanSquaredLoss{}, 1e-5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(check.Layers[0].MaxRelativeError).To(BeNumerically("<", 1e-5))
+			Expect(check.Layers[1].MaxRelativeError).To(BeNumerically("~", 0.5, 1e-3))
+			Expect(check.Layers[1].Worst.Quantity).To(Equal("weight"))
+			Expect(check.MaxRelativeError).To(Equal(check.Layers[1].MaxRelativeError))
+
+			// The weights are left as they were
+			Expect(network.Layers[1].Neurons[2][1].In[3].Weight).To(Equal(weight))
+
+			out := &bytes.Buffer{}
+			Expect(check.WriteText(out)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("weight at ("))
+		})
+
+	this.Should("Return errors from running the network", suite,
+		func() {
+			bad := []*InputConfiguration{&InputConfiguration{Values: [][]float64{{1}}, Expected: [][]float64{{1, 0, 0}}}}
+			_, err := CheckGradients(NewGradientEngine(0.5), newNetwork(&Tanh{}, nil), bad, &MeanSquaredLoss{}, 0)
+			Expect(err).To(MatchError("Input 0: " + ErrArraySizeMismatch.Error()))
+		})
+}
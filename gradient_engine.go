@@ -0,0 +1,481 @@
+package ann
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// DefaultGradientLearningRate is the learning rate of the gradient engine's
+	// optimizer when a spec or the CLI doesn't give one
+	DefaultGradientLearningRate = 0.5
+)
+
+// Gradients stores the gradient of the loss with respect to each connection
+// weight and neuron bias in a network
+type Gradients struct {
+	Biases  map[*Neuron]float64
+	Weights map[*NeuronConnection]float64
+}
+
+// NewGradients creates a new, empty set of gradients
+func NewGradients() *Gradients {
+	return &Gradients{
+		Biases:  make(map[*Neuron]float64),
+		Weights: make(map[*NeuronConnection]float64),
+	}
+}
+
+// Norm returns the L2 norm of all of the gradients together. The squares are
+// added up from smallest to largest, since the maps have no fixed order and
+// the sum has to come out the same for runs with the same seed
+func (g *Gradients) Norm() float64 {
+	squares := make([]float64, 0, len(g.Weights)+len(g.Biases))
+	for _, grad := range g.Weights {
+		squares = append(squares, grad*grad)
+	}
+	for _, grad := range g.Biases {
+		squares = append(squares, grad*grad)
+	}
+	sort.Float64s(squares)
+
+	total := 0.0
+	for _, square := range squares {
+		total += square
+	}
+
+	return math.Sqrt(total)
+}
+
+// ClipValue clips every gradient to [-limit, limit]
+func (g *Gradients) ClipValue(limit float64) {
+	for conn, grad := range g.Weights {
+		g.Weights[conn] = math.Max(-limit, math.Min(limit, grad))
+	}
+	for n, grad := range g.Biases {
+		g.Biases[n] = math.Max(-limit, math.Min(limit, grad))
+	}
+}
+
+// ClipNorm scales all of the gradients down together so their L2 norm is at
+// most limit
+func (g *Gradients) ClipNorm(limit float64) {
+	norm := g.Norm()
+	if norm <= limit || !isFinite(norm) {
+		return
+	}
+
+	scale := limit / norm
+	for conn := range g.Weights {
+		g.Weights[conn] *= scale
+	}
+	for n := range g.Biases {
+		g.Biases[n] *= scale
+	}
+}
+
+// GradientEngine is a network engine that trains using true gradient descent.
+// The error is propagated back through the network using the chain rule and
+// the derivative of each layer's activation
+type GradientEngine struct {
+	// Clipping optionally limits the gradients of every update
+	Clipping  *GradientClipping `json:"clipping"`
+	Debug     bool              `json:"debug"`
+	Optimizer Optimizer         `json:"-"`
+
+	// inputs tracks the weighted input of each neuron from the last run so the
+	// activation derivative can be evaluated during backpropagation
+	inputs map[*Neuron]float64
+
+	// dropout and dropConnect scale the signal of each neuron and the intensity
+	// of each connection in the current training run, zero when dropped and
+	// inverted so the expected signal is unchanged otherwise. Both are nil
+	// outside of training
+	dropout     map[*Neuron]float64
+	dropConnect map[*NeuronConnection]float64
+}
+
+// NewGradientEngine creates a new gradient descent engine that uses plain SGD
+// with the given learning rate
+func NewGradientEngine(learningRate float64) *GradientEngine {
+	return NewGradientEngineWithOptimizer(NewSGD(learningRate))
+}
+
+// NewGradientEngineWithOptimizer creates a new gradient descent engine that
+// applies its gradients with the given optimizer
+func NewGradientEngineWithOptimizer(optimizer Optimizer) *GradientEngine {
+	return &GradientEngine{
+		Optimizer: optimizer,
+		inputs:    make(map[*Neuron]float64),
+	}
+}
+
+// Run performs a forward pass of the network, producing the same output as
+// NeuralNetwork.Run. Unlike NeuralNetwork.Run, binary neurons keep their
+// potential once the run completes. Dropout is only applied while training, so
+// Run never drops anything
+func (e *GradientEngine) Run(input [][]float64, network NetworkConfiguration) error {
+	e.dropout = nil
+	e.dropConnect = nil
+
+	return e.run(input, network)
+}
+
+// runTraining performs a forward pass of the network with a fresh sample of
+// the dropped neurons and connections of each layer
+func (e *GradientEngine) runTraining(input [][]float64, network NetworkConfiguration) error {
+	e.dropout = nil
+	e.dropConnect = nil
+	rng := network.GetRand()
+
+	// The output layer is never dropped out since it is what is being trained
+	layers := network.GetLayers()
+	for i, layer := range layers {
+		if layer.Dropout > 0 && i < len(layers)-1 {
+			if e.dropout == nil {
+				e.dropout = make(map[*Neuron]float64)
+			}
+
+			layer.EachNeuron(func(n *Neuron) {
+				e.dropout[n] = dropScale(rng.Float64(), layer.Dropout)
+			})
+		}
+
+		if layer.DropConnect > 0 {
+			if e.dropConnect == nil {
+				e.dropConnect = make(map[*NeuronConnection]float64)
+			}
+
+			layer.EachNeuron(func(n *Neuron) {
+				for _, conn := range n.In {
+					e.dropConnect[conn] = dropScale(rng.Float64(), layer.DropConnect)
+				}
+			})
+		}
+	}
+
+	return e.run(input, network)
+}
+
+// dropScale returns zero if the pick falls under the rate, dropping whatever
+// it is applied to, and otherwise the scale that keeps its expected value
+// unchanged
+func dropScale(pick, rate float64) float64 {
+	if pick < rate {
+		return 0.0
+	}
+
+	return 1.0 / (1.0 - rate)
+}
+
+// signal returns the neuron's signal scaled by its dropout
+func (e *GradientEngine) signal(n *Neuron) float64 {
+	if scale, ok := e.dropout[n]; ok {
+		return scale * n.Signal()
+	}
+
+	return n.Signal()
+}
+
+// multiplier returns the connection's multiplier scaled by its DropConnect
+func (e *GradientEngine) multiplier(conn *NeuronConnection) float64 {
+	if scale, ok := e.dropConnect[conn]; ok {
+		return scale * conn.Multiplier()
+	}
+
+	return conn.Multiplier()
+}
+
+// run performs a forward pass of the network using the current dropout
+func (e *GradientEngine) run(input [][]float64, network NetworkConfiguration) error {
+	inputLayer := network.GetInput()
+	if len(input) != len(inputLayer.Neurons) ||
+		len(input[0]) != len(inputLayer.Neurons[0]) {
+		return ErrArraySizeMismatch
+	}
+
+	if e.inputs == nil {
+		e.inputs = make(map[*Neuron]float64)
+	}
+
+	network.Clear()
+	inputLayer.EachNeuronWithIndex(func(n *Neuron, row, column int) {
+		n.Potential = input[row][column]
+		e.inputs[n] = n.Potential
+	})
+	inputLayer.Activate()
+	if err := inputLayer.checkPotentials(0); err != nil {
+		return err
+	}
+
+	layers := network.GetLayers()
+	for i := 1; i < len(layers); i++ {
+		layers[i].EachNeuron(func(n *Neuron) {
+			total := n.Bias
+			for _, conn := range n.In {
+				total += e.multiplier(conn) * conn.Weight * e.signal(conn.Source)
+			}
+
+			e.inputs[n] = total
+			n.Potential = total
+		})
+		layers[i].Activate()
+		if err := layers[i].checkPotentials(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CalculateGradients backpropagates the squared error between the expected
+// values and the output of the last run, adding each weight and bias gradient
+// to grads
+func (e *GradientEngine) CalculateGradients(expected [][]float64, network NetworkConfiguration, grads *Gradients) error {
+	return e.CalculateGradientsWithLoss(expected, network, &MeanSquaredLoss{}, grads)
+}
+
+// CalculateGradientsWithLoss backpropagates the given loss between the expected
+// values and the output of the last run, adding each weight and bias gradient
+// to grads
+func (e *GradientEngine) CalculateGradientsWithLoss(expected [][]float64, network NetworkConfiguration, loss Loss, grads *Gradients) error {
+	output := network.GetOutput()
+	if len(expected) != len(output.Neurons) ||
+		len(expected[0]) != len(output.Neurons[0]) {
+		return ErrArraySizeMismatch
+	}
+
+	// The delta of each neuron is the derivative of the loss with respect to
+	// its weighted input. Start with the output layer, where it comes from the
+	// gradient of the loss
+	deltas := make(map[*Neuron]float64)
+	output.EachNeuronWithIndex(func(n *Neuron, row, column int) {
+		deltas[n] = loss.Gradient(expected[row][column], n.Potential)
+	})
+	e.layerDeltas(output, deltas, grads)
+
+	layers := network.GetLayers()
+	for i := len(layers) - 2; i >= 0; i-- {
+		layers[i].EachNeuron(func(n *Neuron) {
+			delta := 0.0
+			for _, conn := range n.Out {
+				tgtDelta := deltas[conn.Target]
+				grads.Weights[conn] += tgtDelta * e.multiplier(conn) * e.signal(n)
+				delta += tgtDelta * (e.multiplier(conn) * conn.Weight)
+			}
+
+			// A dropped neuron's signal doesn't reach the next layer at all
+			if scale, ok := e.dropout[n]; ok {
+				delta *= scale
+			}
+
+			// The signal of a binary neuron is a step, so no error flows through it.
+			// Input neurons have no bias to adjust either
+			if n.Activation != nil && i > 0 {
+				deltas[n] = delta
+			}
+		})
+
+		if i > 0 {
+			e.layerDeltas(layers[i], deltas, grads)
+		}
+	}
+
+	return nil
+}
+
+// layerDeltas turns the gradients of the loss with respect to the outputs of
+// the layer's neurons into their deltas, working back through the layer's
+// softmax and activation, and adds them to the bias gradients
+func (e *GradientEngine) layerDeltas(layer *NetworkLayer, deltas map[*Neuron]float64, grads *Gradients) {
+	if layer.Softmax != nil {
+		layer.Softmax.backward(layer, deltas)
+	}
+
+	layer.EachNeuron(func(n *Neuron) {
+		delta, ok := deltas[n]
+		if !ok {
+			return
+		}
+
+		if n.Activation != nil {
+			delta *= n.Activation.Derivative(e.inputs[n])
+			deltas[n] = delta
+		}
+
+		grads.Biases[n] += delta
+	})
+}
+
+// ApplyGradients averages the provided gradients over the number of samples
+// they were accumulated over, clips them and hands them to the optimizer
+func (e *GradientEngine) ApplyGradients(grads *Gradients, samples int) {
+	e.prepare(grads, samples)
+	e.Optimizer.Update(grads)
+}
+
+// prepare averages the gradients over the number of samples they were
+// accumulated over and clips them, leaving them as the optimizer gets them
+func (e *GradientEngine) prepare(grads *Gradients, samples int) {
+	if samples > 1 {
+		for conn := range grads.Weights {
+			grads.Weights[conn] /= float64(samples)
+		}
+
+		for n := range grads.Biases {
+			grads.Biases[n] /= float64(samples)
+		}
+	}
+
+	if e.Clipping != nil {
+		e.Clipping.apply(grads)
+	}
+}
+
+// update regularizes the gradients accumulated over the given number of
+// samples, applies them and then constrains the weights of each layer with a
+// max norm. The L2 norm of the gradients handed to the optimizer, after
+// regularization, averaging and clipping, is returned along with a
+// NumericError if any weight or bias stops being finite
+func (e *GradientEngine) update(network NetworkConfiguration, grads *Gradients, samples int) (float64, error) {
+	layers := network.GetLayers()
+	for _, layer := range layers {
+		if layer.Regularization != nil {
+			layer.Regularization.addGradients(layer, grads, samples)
+		}
+	}
+
+	e.prepare(grads, samples)
+	norm := grads.Norm()
+	e.Optimizer.Update(grads)
+
+	for _, layer := range layers {
+		if layer.Regularization != nil {
+			layer.Regularization.constrain(layer)
+		}
+	}
+
+	return norm, CheckWeights(network)
+}
+
+// PerformBackPropagation calculates the squared error gradients for the last
+// run against the expected values and immediately applies them
+func (e *GradientEngine) PerformBackPropagation(expected [][]float64, network NetworkConfiguration) error {
+	return e.PerformBackPropagationWithLoss(expected, network, &MeanSquaredLoss{})
+}
+
+// PerformBackPropagationWithLoss calculates the gradients of the given loss for
+// the last run against the expected values and immediately applies them, along
+// with the regularization of each layer
+func (e *GradientEngine) PerformBackPropagationWithLoss(expected [][]float64, network NetworkConfiguration, loss Loss) error {
+	grads := NewGradients()
+	if err := e.CalculateGradientsWithLoss(expected, network, loss, grads); err != nil {
+		errorLog.Println("Error while attempting to backpropagate:", err)
+		return err
+	}
+
+	if _, err := e.update(network, grads, 1); err != nil {
+		errorLog.Println("Error while attempting to backpropagate:", err)
+		return err
+	}
+
+	return nil
+}
+
+// Train trains the network in the given configuration for the specified number
+// of iterations. The gradients of each batch of inputs are accumulated and
+// applied as a single update, regularized by each layer's Regularization and
+// with each layer's Dropout and DropConnect applied to the training runs. The
+// configuration's Schedule sets the learning rate of each iteration. Training
+// stops with a NumericError as soon as a potential, weight or bias stops being
+// finite, rolling back the failed iteration if the configuration's Rollback is
+// set. Training stops sooner if the configuration's
+// early stopping runs out of patience. The history of the run is returned
+func (e *GradientEngine) Train(iterations int, config *TrainingConfiguration) (*History, error) {
+	network := config.Network
+	loss := config.GetLoss()
+
+	// Log 100 frames if we're debugging
+	debugLogTick := iterations / 100
+	if debugLogTick == 0 {
+		debugLogTick = 1
+	}
+
+	if config.Debug {
+		debugLog.Printf("Training with seed %d\n", network.GetSeed())
+	}
+
+	// The schedule works from the optimizer's own learning rate, which is put
+	// back once training is done
+	initial := e.Optimizer.GetLearningRate()
+	defer e.Optimizer.SetLearningRate(initial)
+
+	history := newHistory(config)
+	if len(config.Inputs) == 0 {
+		return history, ErrNoInputs
+	}
+
+	validation := config.newValidationTracker()
+	defer validation.finish()
+	rollback := config.newRollback(e.Optimizer)
+
+	for i := 0; i < iterations; i++ {
+		rollback.save()
+		totalError := 0.0
+		samples := 0
+		e.Optimizer.SetLearningRate(config.scheduledRate(initial, config.Iteration))
+		record := &HistoryRecord{Iteration: config.Iteration, LearningRate: e.Optimizer.GetLearningRate()}
+		batches := config.Batches()
+		for _, batch := range batches {
+			grads := NewGradients()
+			for _, input := range batch {
+				if err := e.runTraining(input.Values, network); err != nil {
+					errorLog.Println("Error while attempting to train:", err)
+					return history, rollback.fail(err)
+				}
+
+				if err := e.CalculateGradientsWithLoss(input.Expected, network, loss, grads); err != nil {
+					errorLog.Println("Error while attempting to backpropagate:", err)
+					return history, rollback.fail(err)
+				}
+
+				totalError += LayerLoss(loss, input.Expected, network.GetOutput())
+			}
+
+			samples += len(batch)
+			norm, err := e.update(network, grads, len(batch))
+			if err != nil {
+				errorLog.Println("Error while attempting to train:", err)
+				return history, rollback.fail(err)
+			}
+			record.GradientNorm += norm / float64(len(batches))
+		}
+
+		if config.Debug && (i%debugLogTick == 0 || i == iterations-1) {
+			debugLog.Printf("Total error: %.3f\n", totalError)
+		}
+
+		// The training loss includes the regularization penalty, since that's
+		// what is being minimized
+		if samples > 0 {
+			record.TrainingLoss = totalError/float64(samples) + RegularizationPenalty(network)
+		}
+
+		eval, stop, err := validation.step(config.Iteration)
+		if err != nil {
+			errorLog.Println("Error while attempting to validate:", err)
+			return history, rollback.fail(err)
+		}
+
+		record.Validation = eval
+		config.observe(record.Validation)
+		history.add(record)
+		config.Iteration++
+		if stop {
+			history.StoppedEarly = true
+			return history, nil
+		}
+	}
+
+	return history, nil
+}
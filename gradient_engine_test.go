@@ -0,0 +1,289 @@
+package ann
+
+import (
+	"math"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestGradientEngine(suite *testing.T) {
+	this.Before(suite, func() {
+		InhibitoryNeuronDensity = 0.0
+	})
+
+	input := [][]float64{
+		[]float64{0.1, 0.9, 1.03},
+		[]float64{0.51, 0.5, 0.5},
+		[]float64{0.9, 0.85, 0.01},
+	}
+
+	expected := [][]float64{
+		[]float64{0.25, 0.5},
+		[]float64{0.75, 0.9},
+	}
+
+	newNetwork := func(activation Activation, sizes ...int) *NeuralNetwork {
+		network := NewNeuralNetwork(0, 0, 0)
+		network.AddConfiguredLayer(LayerConfiguration{
+			Width: 3, Height: 3, Activation: &Identity{}})
+		for i := 0; i < len(sizes); i += 2 {
+			network.AddConfiguredLayer(LayerConfiguration{
+				Width: sizes[i], Height: sizes[i+1], Activation: activation,
+				InhibitoryDensity: InhibitoryNeuronDensity})
+		}
+
+		return network
+	}
+
+	squaredError := func(engine *GradientEngine, network NetworkConfiguration) float64 {
+		engine.Run(input, network)
+		total := 0.0
+		network.GetOutput().EachNeuronWithIndex(func(n *Neuron, row, column int) {
+			total += Evaluator.MeanSquaredError(expected[row][column], n.Potential)
+		})
+
+		return total
+	}
+
+	this.Should("Be usable as a network engine", suite,
+		func() {
+			var engine NetworkEngine = NewGradientEngine(0.5)
+			Expect(engine).ToNot(BeNil())
+		})
+
+	this.Should("Calculate gradients that match a finite difference estimate", suite,
+		func() {
+			InhibitoryNeuronDensity = 0.3
+			activations := []Activation{&Logistic{}, &Tanh{}, &Softplus{}, NewELU(1.0)}
+
+			for _, activation := range activations {
+				network := newNetwork(activation, 4, 4, 2, 2)
+				engine := NewGradientEngine(0.5)
+
+				engine.Run(input, network)
+				grads := NewGradients()
+				Expect(engine.CalculateGradients(expected, network, grads)).To(Succeed())
+
+				epsilon := 1e-6
+				for conn, grad := range grads.Weights {
+					orig := conn.Weight
+					conn.Weight = orig + epsilon
+					plus := squaredError(engine, network)
+					conn.Weight = orig - epsilon
+					minus := squaredError(engine, network)
+					conn.Weight = orig
+
+					numeric := (plus - minus) / (2 * epsilon)
+					Expect(math.Abs(numeric - grad)).To(BeNumerically("<", 1e-6))
+				}
+
+				Expect(grads.Biases).To(HaveLen(4*4 + 2*2))
+				for n, grad := range grads.Biases {
+					orig := n.Bias
+					n.Bias = orig + epsilon
+					plus := squaredError(engine, network)
+					n.Bias = orig - epsilon
+					minus := squaredError(engine, network)
+					n.Bias = orig
+
+					numeric := (plus - minus) / (2 * epsilon)
+					Expect(math.Abs(numeric - grad)).To(BeNumerically("<", 1e-6))
+				}
+			}
+		})
+
+	this.Should("Calculate gradients through the dropped neurons and connections", suite,
+		func() {
+			network := newNetwork(&Tanh{}, 4, 4, 3, 3, 2, 2)
+			network.Layers[1].Dropout = 0.5
+			network.Layers[2].DropConnect = 0.3
+			network.GetOutput().DropConnect = 0.3
+			engine := NewGradientEngine(0.5)
+
+			Expect(engine.runTraining(input, network)).To(Succeed())
+			Expect(engine.dropout).To(HaveLen(4 * 4))
+			Expect(engine.dropConnect).To(HaveLen(4*4*3*3 + 3*3*2*2))
+
+			// Keep the same neurons and connections dropped for the estimate
+			maskedError := func() float64 {
+				engine.run(input, network)
+				total := 0.0
+				network.GetOutput().EachNeuronWithIndex(func(n *Neuron, row, column int) {
+					total += Evaluator.MeanSquaredError(expected[row][column], n.Potential)
+				})
+
+				return total
+			}
+
+			grads := NewGradients()
+			Expect(engine.CalculateGradients(expected, network, grads)).To(Succeed())
+
+			epsilon := 1e-6
+			for conn, grad := range grads.Weights {
+				orig := conn.Weight
+				conn.Weight = orig + epsilon
+				plus := maskedError()
+				conn.Weight = orig - epsilon
+				minus := maskedError()
+				conn.Weight = orig
+
+				numeric := (plus - minus) / (2 * epsilon)
+				Expect(math.Abs(numeric - grad)).To(BeNumerically("<", 1e-6))
+			}
+
+			for n, grad := range grads.Biases {
+				orig := n.Bias
+				n.Bias = orig + epsilon
+				plus := maskedError()
+				n.Bias = orig - epsilon
+				minus := maskedError()
+				n.Bias = orig
+
+				numeric := (plus - minus) / (2 * epsilon)
+				Expect(math.Abs(numeric - grad)).To(BeNumerically("<", 1e-6))
+			}
+		})
+
+	this.Should("Drop neurons at the configured rate and scale up the rest", suite,
+		func() {
+			network := newNetwork(&Logistic{}, 20, 20, 1, 1)
+			network.Layers[1].Dropout = 0.25
+			network.GetOutput().Dropout = 0.5
+			engine := NewGradientEngine(0.5)
+			engine.runTraining(input, network)
+
+			dropped := 0
+			network.Layers[1].EachNeuron(func(n *Neuron) {
+				if engine.dropout[n] == 0 {
+					dropped++
+				} else {
+					Expect(engine.dropout[n]).To(BeNumerically("~", 1/0.75, 1e-12))
+				}
+			})
+			Expect(dropped).To(BeNumerically("~", 100, 30))
+
+			// The output layer is what's being trained, so it's never dropped
+			_, ok := engine.dropout[network.GetOutput().Neurons[0][0]]
+			Expect(ok).To(BeFalse())
+		})
+
+	this.Should("Never drop anything outside of training", suite,
+		func() {
+			network := newNetwork(&Tanh{}, 4, 4, 2, 2)
+			network.Layers[1].Dropout = 0.5
+			network.GetOutput().DropConnect = 0.5
+			engine := NewGradientEngine(0.5)
+
+			engine.runTraining(input, network)
+			network.Run(input)
+			direct := network.Clone()
+			engine.Run(input, network)
+			Expect(engine.dropout).To(BeNil())
+			Expect(engine.dropConnect).To(BeNil())
+
+			network.GetOutput().EachNeuronWithIndex(func(n *Neuron, row, column int) {
+				Expect(n.Potential).To(BeNumerically("~",
+					direct.GetOutput().Neurons[row][column].Potential, 1e-12))
+			})
+		})
+
+	this.Should("Leave dropout to the gradient engine", suite,
+		func() {
+			for _, drop := range []func(layer *NetworkLayer){
+				func(layer *NetworkLayer) { layer.Dropout = 0.5 },
+				func(layer *NetworkLayer) { layer.DropConnect = 0.5 },
+			} {
+				network := newNetwork(&Tanh{}, 4, 4, 2, 2)
+				drop(network.Layers[1])
+				config := &TrainingConfiguration{
+					Inputs:  []*InputConfiguration{&InputConfiguration{Values: input, Expected: expected, Weight: 1}},
+					Network: network,
+				}
+
+				_, err := Evaluator.Train(1, config)
+				Expect(err).To(MatchError("Layer 1: the default engine doesn't support dropout"))
+			}
+		})
+
+	this.Should("Produce the same output as running the network directly", suite,
+		func() {
+			InhibitoryNeuronDensity = 0.3
+			network := newNetwork(&Tanh{}, 4, 4, 2, 2)
+			engine := NewGradientEngine(0.5)
+
+			network.Run(input)
+			direct := network.Clone()
+			engine.Run(input, network)
+
+			network.GetOutput().EachNeuronWithIndex(func(n *Neuron, row, column int) {
+				Expect(n.Potential).To(BeNumerically("~",
+					direct.GetOutput().Neurons[row][column].Potential, 1e-12))
+			})
+		})
+
+	this.Should("Return an error when the expected values mismatch the output layer", suite,
+		func() {
+			network := NewNeuralNetwork(2, 2, 2)
+			engine := NewGradientEngine(0.5)
+
+			engine.Run([][]float64{[]float64{1.0, 1.0}, []float64{1.0, 1.0}}, network)
+			err := engine.PerformBackPropagation(input, network)
+			Expect(err).To(Equal(ErrArraySizeMismatch))
+		})
+
+	this.Should("Reduce the error on a simple network", suite,
+		func() {
+			network := newNetwork(&Logistic{}, 3, 3, 2, 2)
+			engine := NewGradientEngine(0.5)
+
+			config := &TrainingConfiguration{
+				Engine: engine,
+				Inputs: []*InputConfiguration{
+					&InputConfiguration{
+						Expected: expected,
+						Values:   input,
+						Weight:   1.0,
+					},
+				},
+				Network: network,
+			}
+
+			before := squaredError(engine, network)
+			engine.Train(5000, config)
+			after := squaredError(engine, network)
+
+			Expect(after).To(BeNumerically("<", before))
+			Expect(after).To(BeNumerically("<", 0.001))
+		})
+
+	this.Should("Learn an offset from an all zero input using the biases", suite,
+		func() {
+			network := newNetwork(&Tanh{}, 3, 3)
+			network.AddConfiguredLayer(LayerConfiguration{
+				Width: 1, Height: 1, Activation: &Identity{}})
+			engine := NewGradientEngine(0.1)
+
+			zero := [][]float64{
+				[]float64{0.0, 0.0, 0.0},
+				[]float64{0.0, 0.0, 0.0},
+				[]float64{0.0, 0.0, 0.0},
+			}
+
+			config := &TrainingConfiguration{
+				Inputs: []*InputConfiguration{
+					&InputConfiguration{
+						Expected: [][]float64{[]float64{0.7}},
+						Values:   zero,
+						Weight:   1.0,
+					},
+				},
+				Network: network,
+			}
+
+			engine.Train(2000, config)
+			network.Run(zero)
+			Expect(network.GetOutput().Neurons[0][0].Potential).To(BeNumerically("~", 0.7, 1e-3))
+		})
+}
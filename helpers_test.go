@@ -0,0 +1,37 @@
+package ann
+
+// newTestNetwork builds a network whose random source is seeded with the
+// given seed, adding a layer for each configuration in order. The first
+// configuration is the input layer
+func newTestNetwork(seed int64, layers ...LayerConfiguration) *NeuralNetwork {
+	network := NewNeuralNetworkWithSeed(0, 0, 0, seed)
+	for _, layer := range layers {
+		network.AddConfiguredLayer(layer)
+	}
+
+	return network
+}
+
+// randomizeBiases draws a bias in [-0.5, 0.5) for every neuron of the network
+// from the network's random source, so the same seed gives the same biases
+func randomizeBiases(network *NeuralNetwork) {
+	network.EachLayer(func(layer *NetworkLayer) {
+		layer.EachNeuron(func(n *Neuron) {
+			n.Bias = network.GetRand().Float64() - 0.5
+		})
+	})
+}
+
+// newTestInput draws an input grid for the network's input layer from the
+// network's random source, with every value in [-scale, scale)
+func newTestInput(network *NeuralNetwork, scale float64) [][]float64 {
+	input := make([][]float64, network.GetInput().Width())
+	for row := range input {
+		input[row] = make([]float64, network.GetInput().Height())
+		for column := range input[row] {
+			input[row][column] = (network.GetRand().Float64()*2 - 1) * scale
+		}
+	}
+
+	return input
+}
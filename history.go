@@ -0,0 +1,99 @@
+package ann
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+// History is the record of a training run returned by Train
+type History struct {
+	Records []*HistoryRecord `json:"records"`
+	Seed    int64            `json:"seed"`
+
+	// StoppedEarly is whether early stopping ended the run before all of its
+	// iterations
+	StoppedEarly bool `json:"stopped_early"`
+
+	start time.Time
+}
+
+// HistoryRecord is the state of training after a single iteration, or epoch
+// with SamplingEpoch
+type HistoryRecord struct {
+	// GradientNorm is the L2 norm of the gradients applied in the iteration,
+	// after regularization and clipping, averaged over its batches. It's zero
+	// for engines that don't calculate gradients
+	GradientNorm float64 `json:"gradient_norm"`
+	Iteration    int     `json:"iteration"`
+
+	// LearningRate is the engine's learning rate for the iteration, as set by
+	// the training configuration's schedule
+	LearningRate float64 `json:"learning_rate"`
+
+	// TrainingLoss is the average loss per input trained on in the iteration,
+	// plus the network's regularization penalty for the gradient engine
+	TrainingLoss float64 `json:"training_loss"`
+
+	// Validation is the evaluation of the validation set, if it was evaluated
+	// after the iteration
+	Validation *Evaluation `json:"validation,omitempty"`
+
+	// WallSeconds is the time since training started in seconds
+	WallSeconds float64 `json:"wall_seconds"`
+}
+
+// newHistory starts the history of a training run
+func newHistory(config *TrainingConfiguration) *History {
+	return &History{
+		Records: make([]*HistoryRecord, 0),
+		Seed:    config.Network.GetSeed(),
+		start:   time.Now(),
+	}
+}
+
+// add records an iteration of training
+func (h *History) add(record *HistoryRecord) {
+	record.WallSeconds = time.Since(h.start).Seconds()
+	h.Records = append(h.Records, record)
+}
+
+// WriteCSV writes the records as CSV with a header row. The validation columns
+// are empty for iterations that weren't evaluated
+func (h *History) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"iteration", "training_loss", "validation_loss",
+		"validation_accuracy", "learning_rate", "gradient_norm", "wall_seconds"})
+
+	format := func(val float64) string {
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	}
+
+	for _, record := range h.Records {
+		validationLoss, validationAccuracy := "", ""
+		if record.Validation != nil {
+			validationLoss = format(record.Validation.Loss)
+			validationAccuracy = format(record.Validation.Accuracy)
+		}
+
+		out.Write([]string{
+			strconv.Itoa(record.Iteration),
+			format(record.TrainingLoss),
+			validationLoss,
+			validationAccuracy,
+			format(record.LearningRate),
+			format(record.GradientNorm),
+			format(record.WallSeconds),
+		})
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSON writes the history as JSON
+func (h *History) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(h)
+}
@@ -0,0 +1,159 @@
+package ann
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestHistory(suite *testing.T) {
+	newConfig := func(engine NetworkEngine) *TrainingConfiguration {
+		network := NewNeuralNetworkWithSeed(0, 0, 0, 3)
+		network.AddConfiguredLayer(LayerConfiguration{Width: 2, Height: 1, Activation: &Identity{}})
+		network.AddConfiguredLayer(LayerConfiguration{Width: 1, Height: 1, Activation: &Logistic{}})
+
+		inputs := []*InputConfiguration{
+			&InputConfiguration{
+				Values:   [][]float64{[]float64{1}, []float64{0}},
+				Expected: [][]float64{[]float64{1}},
+				Weight:   1,
+			},
+			&InputConfiguration{
+				Values:   [][]float64{[]float64{0}, []float64{1}},
+				Expected: [][]float64{[]float64{0}},
+				Weight:   1,
+			},
+		}
+
+		return &TrainingConfiguration{
+			BatchSize:          FullBatch,
+			Engine:             engine,
+			Inputs:             inputs,
+			Network:            network,
+			Sampling:           SamplingEpoch,
+			Validation:         inputs,
+			ValidationInterval: 5,
+		}
+	}
+
+	this.Should("Record every iteration of a gradient engine run", suite,
+		func() {
+			config := newConfig(NewGradientEngine(0.5))
+			history, err := config.Engine.Train(20, config)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(history.Seed).To(Equal(int64(3)))
+			Expect(history.StoppedEarly).To(BeFalse())
+			Expect(history.Records).To(HaveLen(20))
+
+			for i, record := range history.Records {
+				Expect(record.Iteration).To(Equal(i))
+				Expect(record.LearningRate).To(Equal(0.5))
+				Expect(record.GradientNorm).To(BeNumerically(">", 0))
+				Expect(record.WallSeconds).To(BeNumerically(">=", 0))
+				if i%5 == 4 {
+					Expect(record.Validation).ToNot(BeNil())
+				} else {
+					Expect(record.Validation).To(BeNil())
+				}
+			}
+
+			first, last := history.Records[0], history.Records[19]
+			Expect(last.TrainingLoss).To(BeNumerically("<", first.TrainingLoss))
+			Expect(last.WallSeconds).To(BeNumerically(">=", first.WallSeconds))
+
+			// With the full batch, the training loss is the loss before the update
+			// that the validation evaluation just missed
+			Expect(history.Records[5].TrainingLoss).To(BeNumerically("~", history.Records[4].Validation.Loss, 1e-12))
+		})
+
+	this.Should("Record the norm of the clipped gradients that were applied", suite,
+		func() {
+			config := newConfig(NewGradientEngine(0.5))
+			history, err := config.Engine.Train(1, config)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history.Records[0].GradientNorm).To(BeNumerically(">", 0.01))
+
+			config = newConfig(NewGradientEngine(0.5))
+			config.Engine.(*GradientEngine).Clipping = &GradientClipping{Norm: 0.01}
+			history, err = config.Engine.Train(1, config)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(history.Records[0].GradientNorm).To(BeNumerically("~", 0.01, 1e-12))
+		})
+
+	this.Should("Record the training loss of the default evaluator", suite,
+		func() {
+			config := newConfig(Evaluator)
+			config.BatchSize = 1
+			config.EarlyStopping = &EarlyStopping{Patience: 0, MinDelta: 100}
+			history, err := config.Engine.Train(20, config)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(history.StoppedEarly).To(BeTrue())
+			Expect(history.Records).To(HaveLen(10))
+			Expect(history.Records[0].TrainingLoss).To(BeNumerically(">", 0))
+			Expect(history.Records[0].GradientNorm).To(Equal(0.0))
+			Expect(history.Records[0].LearningRate).To(Equal(DefaultEvaluatorLearningRate))
+		})
+
+	this.Should("Refuse batches with the default evaluator even when it isn't the configured engine", suite,
+		func() {
+			for _, batch := range []int{2, FullBatch} {
+				config := newConfig(nil)
+				config.BatchSize = batch
+				config.Sampling = SamplingEpoch
+				_, err := Evaluator.Train(1, config)
+				Expect(err).To(MatchError(ContainSubstring("doesn't support batches")), batch)
+			}
+		})
+
+	this.Should("Refuse to train without any inputs", suite,
+		func() {
+			for _, sampling := range []int{SamplingWeighted, SamplingEpoch} {
+				for _, engine := range []NetworkEngine{NewGradientEngine(0.5), Evaluator} {
+					config := newConfig(engine)
+					config.BatchSize = 1
+					config.Inputs = nil
+					config.Sampling = sampling
+					history, err := engine.Train(5, config)
+					Expect(err).To(Equal(ErrNoInputs))
+					Expect(history.Records).To(BeEmpty())
+				}
+			}
+		})
+
+	this.Should("Write the history as CSV and JSON", suite,
+		func() {
+			config := newConfig(NewGradientEngine(0.5))
+			history, err := config.Engine.Train(5, config)
+			Expect(err).ToNot(HaveOccurred())
+
+			buf := &bytes.Buffer{}
+			Expect(history.WriteCSV(buf)).To(Succeed())
+			rows, err := csv.NewReader(buf).ReadAll()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rows).To(HaveLen(6))
+			Expect(rows[0]).To(Equal([]string{"iteration", "training_loss", "validation_loss",
+				"validation_accuracy", "learning_rate", "gradient_norm", "wall_seconds"}))
+			Expect(rows[1][2]).To(BeEmpty())
+			Expect(rows[5][0]).To(Equal("4"))
+			Expect(rows[5][2]).ToNot(BeEmpty())
+
+			buf.Reset()
+			Expect(history.WriteJSON(buf)).To(Succeed())
+			loaded := &History{}
+			Expect(json.Unmarshal(buf.Bytes(), loaded)).To(Succeed())
+			Expect(loaded.Records).To(HaveLen(5))
+			Expect(loaded.Records[4].Validation).To(Equal(history.Records[4].Validation))
+			Expect(loaded.Records[2].TrainingLoss).To(Equal(history.Records[2].TrainingLoss))
+
+			// Both writers give the wall time in seconds
+			Expect(buf.String()).To(ContainSubstring(`"wall_seconds":`))
+			Expect(rows[5][6]).To(Equal(strconv.FormatFloat(loaded.Records[4].WallSeconds, 'g', -1, 64)))
+		})
+}
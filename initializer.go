@@ -0,0 +1,344 @@
+package ann
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+var (
+	// ErrUnknownInitializer is the error for when a weight initializer is
+	// requested by a name that isn't registered
+	ErrUnknownInitializer = errors.New("Unknown weight initializer")
+)
+
+// Initializer sets the starting weights of the connections coming in to a
+// layer. The fan in of a connection is the number of connections coming in to
+// its target and the fan out is the number going out of its source
+type Initializer interface {
+	Initialize(layer *NetworkLayer) error
+	Name() string
+}
+
+// InitializerByName returns the built-in initializer registered under the
+// given name, using the default parameters for any that are configurable
+func InitializerByName(name string) (Initializer, error) {
+	switch name {
+	case "constant":
+		return NewConstantInitializer(NeuronConnectionWeight), nil
+	case "he", "kaiming":
+		return &HeInitializer{}, nil
+	case "lecun":
+		return &LeCunInitializer{}, nil
+	case "normal":
+		return NewNormalInitializer(0.0, 1.0), nil
+	case "orthogonal":
+		return NewOrthogonalInitializer(1.0), nil
+	case "uniform":
+		return NewUniformInitializer(0.0, 1.0), nil
+	case "xavier", "glorot":
+		return &XavierInitializer{}, nil
+	case "zero":
+		return &ZeroInitializer{}, nil
+	}
+
+	return nil, ErrUnknownInitializer
+}
+
+// eachIncoming performs some action on every connection coming in to the layer
+func eachIncoming(layer *NetworkLayer, do func(conn *NeuronConnection)) {
+	layer.EachNeuron(func(n *Neuron) {
+		for _, conn := range n.In {
+			do(conn)
+		}
+	})
+}
+
+// scaleVariance draws every incoming weight of the layer from a zero centered
+// distribution with the variance returned for each connection's fan in and fan
+// out. Uniform distributions are scaled to the same variance
+func scaleVariance(layer *NetworkLayer, uniform bool, variance func(fanIn, fanOut float64) float64) {
+	rng := layer.random()
+	eachIncoming(layer, func(conn *NeuronConnection) {
+		v := variance(float64(len(conn.Target.In)), float64(len(conn.Source.Out)))
+		if uniform {
+			limit := math.Sqrt(3 * v)
+			conn.Weight = (2*rng.Float64() - 1) * limit
+		} else {
+			conn.Weight = rng.NormFloat64() * math.Sqrt(v)
+		}
+	})
+}
+
+// ConstantInitializer sets every weight to the same value
+type ConstantInitializer struct {
+	Value float64 `json:"value"`
+}
+
+// NewConstantInitializer creates a new initializer that sets every weight to
+// the given value
+func NewConstantInitializer(value float64) *ConstantInitializer {
+	return &ConstantInitializer{Value: value}
+}
+
+// Initialize sets the weights of the layer's incoming connections
+func (i *ConstantInitializer) Initialize(layer *NetworkLayer) error {
+	eachIncoming(layer, func(conn *NeuronConnection) {
+		conn.Weight = i.Value
+	})
+
+	return nil
+}
+
+// Name returns the registered name of the initializer
+func (i *ConstantInitializer) Name() string {
+	return "constant"
+}
+
+// HeInitializer draws weights with a variance of 2 / fan in, which keeps the
+// signal of rectified layers from dying out. Weights are normally distributed
+// unless Uniform is set
+type HeInitializer struct {
+	Uniform bool `json:"uniform"`
+}
+
+// Initialize sets the weights of the layer's incoming connections
+func (i *HeInitializer) Initialize(layer *NetworkLayer) error {
+	scaleVariance(layer, i.Uniform, func(fanIn, fanOut float64) float64 {
+		return 2 / fanIn
+	})
+
+	return nil
+}
+
+// Name returns the registered name of the initializer
+func (i *HeInitializer) Name() string {
+	return "he"
+}
+
+// LeCunInitializer draws weights with a variance of 1 / fan in. Weights are
+// normally distributed unless Uniform is set
+type LeCunInitializer struct {
+	Uniform bool `json:"uniform"`
+}
+
+// Initialize sets the weights of the layer's incoming connections
+func (i *LeCunInitializer) Initialize(layer *NetworkLayer) error {
+	scaleVariance(layer, i.Uniform, func(fanIn, fanOut float64) float64 {
+		return 1 / fanIn
+	})
+
+	return nil
+}
+
+// Name returns the registered name of the initializer
+func (i *LeCunInitializer) Name() string {
+	return "lecun"
+}
+
+// NormalInitializer draws weights from a normal distribution
+type NormalInitializer struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+}
+
+// NewNormalInitializer creates a new initializer that draws weights from a
+// normal distribution with the given mean and standard deviation
+func NewNormalInitializer(mean, stdDev float64) *NormalInitializer {
+	return &NormalInitializer{Mean: mean, StdDev: stdDev}
+}
+
+// Initialize sets the weights of the layer's incoming connections
+func (i *NormalInitializer) Initialize(layer *NetworkLayer) error {
+	if i.StdDev < 0 {
+		return fmt.Errorf("Invalid normal init standard deviation %v", i.StdDev)
+	}
+
+	rng := layer.random()
+	eachIncoming(layer, func(conn *NeuronConnection) {
+		conn.Weight = i.Mean + rng.NormFloat64()*i.StdDev
+	})
+
+	return nil
+}
+
+// Name returns the registered name of the initializer
+func (i *NormalInitializer) Name() string {
+	return "normal"
+}
+
+// OrthogonalInitializer sets the weights to a random orthogonal matrix, scaled
+// by Gain, with a row for each neuron in the layer and a column for each
+// source neuron. Connections that aren't wired up just drop their entry of the
+// matrix
+type OrthogonalInitializer struct {
+	Gain float64 `json:"gain"`
+}
+
+// NewOrthogonalInitializer creates a new orthogonal initializer with the given
+// gain
+func NewOrthogonalInitializer(gain float64) *OrthogonalInitializer {
+	return &OrthogonalInitializer{Gain: gain}
+}
+
+// Initialize sets the weights of the layer's incoming connections
+func (i *OrthogonalInitializer) Initialize(layer *NetworkLayer) error {
+	sources := make(map[*Neuron]int)
+	targets := make([]*Neuron, 0)
+	layer.EachNeuron(func(n *Neuron) {
+		targets = append(targets, n)
+		for _, conn := range n.In {
+			if _, ok := sources[conn.Source]; !ok {
+				sources[conn.Source] = len(sources)
+			}
+		}
+	})
+
+	if len(sources) == 0 {
+		return nil
+	}
+
+	matrix := orthogonalMatrix(len(targets), len(sources), layer.random())
+	for row, n := range targets {
+		for _, conn := range n.In {
+			conn.Weight = i.Gain * matrix[row][sources[conn.Source]]
+		}
+	}
+
+	return nil
+}
+
+// Name returns the registered name of the initializer
+func (i *OrthogonalInitializer) Name() string {
+	return "orthogonal"
+}
+
+// orthogonalMatrix returns a random matrix whose rows are orthonormal, or
+// whose columns are if there are more rows than columns
+func orthogonalMatrix(rows, columns int, rng *rand.Rand) [][]float64 {
+	// Orthonormalize the shorter side of a gaussian matrix with Gram-Schmidt
+	count, size := rows, columns
+	if rows > columns {
+		count, size = columns, rows
+	}
+
+	vectors := make([][]float64, count)
+	for i := 0; i < count; i++ {
+		for {
+			vector := make([]float64, size)
+			for j := range vector {
+				vector[j] = rng.NormFloat64()
+			}
+
+			for _, prev := range vectors[:i] {
+				dot := 0.0
+				for j := range vector {
+					dot += vector[j] * prev[j]
+				}
+				for j := range vector {
+					vector[j] -= dot * prev[j]
+				}
+			}
+
+			norm := 0.0
+			for _, val := range vector {
+				norm += val * val
+			}
+			norm = math.Sqrt(norm)
+
+			// Draw again in the unlikely case the vector was almost dependent on
+			// the previous ones
+			if norm > 1e-6 {
+				for j := range vector {
+					vector[j] /= norm
+				}
+				vectors[i] = vector
+				break
+			}
+		}
+	}
+
+	matrix := make([][]float64, rows)
+	for row := range matrix {
+		matrix[row] = make([]float64, columns)
+		for column := range matrix[row] {
+			if rows > columns {
+				matrix[row][column] = vectors[column][row]
+			} else {
+				matrix[row][column] = vectors[row][column]
+			}
+		}
+	}
+
+	return matrix
+}
+
+// UniformInitializer draws weights uniformly from [Min, Max)
+type UniformInitializer struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// NewUniformInitializer creates a new initializer that draws weights uniformly
+// from [min, max)
+func NewUniformInitializer(min, max float64) *UniformInitializer {
+	return &UniformInitializer{Min: min, Max: max}
+}
+
+// Initialize sets the weights of the layer's incoming connections
+func (i *UniformInitializer) Initialize(layer *NetworkLayer) error {
+	if i.Max < i.Min {
+		return fmt.Errorf("Invalid uniform init range [%v, %v)", i.Min, i.Max)
+	}
+
+	rng := layer.random()
+	eachIncoming(layer, func(conn *NeuronConnection) {
+		conn.Weight = i.Min + rng.Float64()*(i.Max-i.Min)
+	})
+
+	return nil
+}
+
+// Name returns the registered name of the initializer
+func (i *UniformInitializer) Name() string {
+	return "uniform"
+}
+
+// XavierInitializer, also known as Glorot initialization, draws weights with a
+// variance of 2 / (fan in + fan out), which suits logistic and tanh layers.
+// Weights are normally distributed unless Uniform is set
+type XavierInitializer struct {
+	Uniform bool `json:"uniform"`
+}
+
+// Initialize sets the weights of the layer's incoming connections
+func (i *XavierInitializer) Initialize(layer *NetworkLayer) error {
+	scaleVariance(layer, i.Uniform, func(fanIn, fanOut float64) float64 {
+		return 2 / (fanIn + fanOut)
+	})
+
+	return nil
+}
+
+// Name returns the registered name of the initializer
+func (i *XavierInitializer) Name() string {
+	return "xavier"
+}
+
+// ZeroInitializer sets every weight to zero
+type ZeroInitializer struct{}
+
+// Initialize sets the weights of the layer's incoming connections
+func (i *ZeroInitializer) Initialize(layer *NetworkLayer) error {
+	eachIncoming(layer, func(conn *NeuronConnection) {
+		conn.Weight = 0
+	})
+
+	return nil
+}
+
+// Name returns the registered name of the initializer
+func (i *ZeroInitializer) Name() string {
+	return "zero"
+}
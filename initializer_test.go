@@ -0,0 +1,165 @@
+package ann
+
+import (
+	"math"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestInitializers(suite *testing.T) {
+	names := []string{"constant", "he", "lecun", "normal", "orthogonal", "uniform",
+		"xavier", "zero"}
+
+	this.Before(suite, func() {
+		InhibitoryNeuronDensity = 0.0
+	})
+
+	newNetwork := func(initializer Initializer, width, height int) *NeuralNetwork {
+		network := NewNeuralNetwork(0, 0, 0)
+		network.AddConfiguredLayer(LayerConfiguration{Width: 10, Height: 10, Activation: &Identity{}})
+		_, err := network.AddConfiguredLayer(LayerConfiguration{
+			Width: width, Height: height, Activation: &ReLU{}, Initializer: initializer})
+		Expect(err).ToNot(HaveOccurred())
+
+		return network
+	}
+
+	weights := func(network *NeuralNetwork) []float64 {
+		all := make([]float64, 0)
+		network.GetOutput().EachNeuron(func(n *Neuron) {
+			for _, conn := range n.In {
+				all = append(all, conn.Weight)
+			}
+		})
+
+		return all
+	}
+
+	variance := func(values []float64) float64 {
+		mean := 0.0
+		for _, val := range values {
+			mean += val
+		}
+		mean /= float64(len(values))
+
+		total := 0.0
+		for _, val := range values {
+			total += (val - mean) * (val - mean)
+		}
+
+		return total / float64(len(values))
+	}
+
+	this.Should("Look up each built-in initializer by name", suite,
+		func() {
+			for _, name := range names {
+				initializer, err := InitializerByName(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(initializer.Name()).To(Equal(name))
+			}
+
+			_, err := InitializerByName("magic")
+			Expect(err).To(Equal(ErrUnknownInitializer))
+		})
+
+	this.Should("Set constant and zero weights", suite,
+		func() {
+			for _, weight := range weights(newNetwork(NewConstantInitializer(0.3), 4, 4)) {
+				Expect(weight).To(Equal(0.3))
+			}
+
+			for _, weight := range weights(newNetwork(&ZeroInitializer{}, 4, 4)) {
+				Expect(weight).To(Equal(0.0))
+			}
+		})
+
+	this.Should("Draw weights within an explicit range", suite,
+		func() {
+			for _, weight := range weights(newNetwork(NewUniformInitializer(-0.2, 0.1), 4, 4)) {
+				Expect(weight).To(BeNumerically(">=", -0.2))
+				Expect(weight).To(BeNumerically("<", 0.1))
+			}
+
+			drawn := weights(newNetwork(NewNormalInitializer(0.5, 0.01), 10, 10))
+			Expect(variance(drawn)).To(BeNumerically("~", 0.0001, 0.00003))
+
+			network := NewNeuralNetwork(0, 0, 0)
+			network.AddLayer(2, 2)
+			_, err := network.AddConfiguredLayer(LayerConfiguration{
+				Width: 2, Height: 2, Initializer: NewUniformInitializer(1, 0)})
+			Expect(err).To(HaveOccurred())
+		})
+
+	this.Should("Scale the variance with the fan in and fan out", suite,
+		func() {
+			// Every connection from the 100 input neurons to the 10x10 layer has a
+			// fan in and fan out of 100
+			cases := []struct {
+				initializer Initializer
+				variance    float64
+			}{
+				{&HeInitializer{}, 2.0 / 100},
+				{&HeInitializer{Uniform: true}, 2.0 / 100},
+				{&LeCunInitializer{}, 1.0 / 100},
+				{&XavierInitializer{}, 2.0 / 200},
+				{&XavierInitializer{Uniform: true}, 2.0 / 200},
+			}
+
+			for _, c := range cases {
+				drawn := weights(newNetwork(c.initializer, 10, 10))
+				Expect(variance(drawn)).To(BeNumerically("~", c.variance, c.variance*0.1))
+			}
+
+			limit := math.Sqrt(6.0 / 100)
+			for _, weight := range weights(newNetwork(&HeInitializer{Uniform: true}, 10, 10)) {
+				Expect(math.Abs(weight)).To(BeNumerically("<=", limit))
+			}
+		})
+
+	this.Should("Produce orthogonal weight matrices", suite,
+		func() {
+			for _, shape := range [][2]int{{10, 10}, {2, 3}, {20, 8}} {
+				network := newNetwork(NewOrthogonalInitializer(2.0), shape[0], shape[1])
+				rows := make([][]float64, 0)
+				network.GetOutput().EachNeuron(func(n *Neuron) {
+					row := make([]float64, len(n.In))
+					for i, conn := range n.In {
+						row[i] = conn.Weight / 2.0
+					}
+					rows = append(rows, row)
+				})
+
+				// The shorter side of the matrix is orthonormal
+				dot := func(i, j int) float64 {
+					total := 0.0
+					if len(rows) <= len(rows[0]) {
+						for k := range rows[i] {
+							total += rows[i][k] * rows[j][k]
+						}
+					} else {
+						for k := range rows {
+							total += rows[k][i] * rows[k][j]
+						}
+					}
+					return total
+				}
+
+				count := len(rows)
+				if count > len(rows[0]) {
+					count = len(rows[0])
+				}
+
+				for i := 0; i < count; i++ {
+					for j := 0; j < count; j++ {
+						expected := 0.0
+						if i == j {
+							expected = 1.0
+						}
+						Expect(dot(i, j)).To(BeNumerically("~", expected, 1e-9))
+					}
+				}
+			}
+		})
+}
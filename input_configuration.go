@@ -1,4 +1,4 @@
-package main
+package ann
 
 // InputConfiguration stores the necessary data to run training simulations
 // on a network
@@ -0,0 +1,76 @@
+package ann
+
+import "fmt"
+
+const (
+	// ConnectFull connects every neuron of the previous layer to every neuron of
+	// the new layer
+	ConnectFull = "full"
+
+	// ConnectLocal connects each neuron of the new layer to a window of neurons
+	// around the matching position in the previous layer
+	ConnectLocal = "local"
+
+	// ConnectOneToOne connects each neuron of the new layer to the neuron at the
+	// same position in the previous layer
+	ConnectOneToOne = "one_to_one"
+)
+
+// LayerConfiguration describes how a new layer of the network should be built
+type LayerConfiguration struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Activation is applied to each neuron's total input. Leaving it nil builds
+	// a layer of binary neurons. Note that an input layer with binary neurons
+	// only passes on whether each input crosses the PotentialThreshold, so
+	// networks of activated layers will usually want an Identity input layer
+	Activation Activation `json:"-"`
+
+	// Connectivity is how the layer is wired to the previous one, defaulting to
+	// ConnectFull. Radius is the reach of each window for ConnectLocal
+	Connectivity string `json:"connectivity"`
+	Radius       int    `json:"radius"`
+
+	// Dropout is the fraction of the layer's neurons that are dropped from each
+	// training run, ignored for the output layer. DropConnect is the fraction of
+	// its incoming connections dropped instead. Neither applies outside of
+	// training
+	Dropout     float64 `json:"dropout"`
+	DropConnect float64 `json:"drop_connect"`
+
+	// InhibitoryDensity is the density with which to create inhibitory neurons
+	InhibitoryDensity float64 `json:"inhibitory_density"`
+
+	// Initializer sets the starting weights of the connections coming in to the
+	// layer. Leaving it nil keeps the weights the connectivity starts them with
+	Initializer Initializer `json:"-"`
+
+	// Regularization penalizes or constrains the weights coming in to the layer
+	// while training
+	Regularization *Regularization `json:"regularization"`
+
+	// Softmax normalizes the layer's potentials into probabilities, usually for
+	// the output layer of a classifier
+	Softmax *Softmax `json:"softmax"`
+}
+
+// connect wires the previous layer up to the new layer using the configured
+// connectivity
+func (c LayerConfiguration) connect(prev, layer *NetworkLayer) error {
+	switch c.Connectivity {
+	case "", ConnectFull:
+		prev.Connect(layer)
+	case ConnectLocal:
+		if c.Radius < 0 {
+			return fmt.Errorf("Invalid local connectivity radius %d", c.Radius)
+		}
+		prev.ConnectLocal(layer, c.Radius)
+	case ConnectOneToOne:
+		return prev.ConnectOneToOne(layer)
+	default:
+		return fmt.Errorf("Unknown connectivity %q", c.Connectivity)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package ann
+
+import (
+	"io"
+	"log"
+)
+
+// Loggers used by the package. These discard everything by default so that
+// importing the library stays quiet until SetLoggers is called
+var (
+	debugLog = newDiscardLogger()
+	infoLog  = newDiscardLogger()
+	warnLog  = newDiscardLogger()
+	errorLog = newDiscardLogger()
+)
+
+// SetLoggers injects the loggers used by the package. Any nil logger is
+// replaced with one that discards its output
+func SetLoggers(debug, info, warn, err *log.Logger) {
+	debugLog = orDiscard(debug)
+	infoLog = orDiscard(info)
+	warnLog = orDiscard(warn)
+	errorLog = orDiscard(err)
+}
+
+func newDiscardLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+func orDiscard(logger *log.Logger) *log.Logger {
+	if logger == nil {
+		return newDiscardLogger()
+	}
+
+	return logger
+}
@@ -0,0 +1,250 @@
+package ann
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	// lossEpsilon keeps the logarithms of the cross entropy losses finite
+	lossEpsilon = 1e-12
+)
+
+var (
+	// ErrUnknownLoss is the error for when a loss function is requested by a
+	// name that isn't registered
+	ErrUnknownLoss = errors.New("Unknown loss function")
+)
+
+// Loss measures how far a single output is from its expected value. The loss
+// of a whole output layer is the sum of the loss of each of its neurons
+type Loss interface {
+	// Gradient is the derivative of the loss with respect to the actual value
+	Gradient(expected, actual float64) float64
+	Name() string
+	Value(expected, actual float64) float64
+}
+
+// LossByName returns the built-in loss registered under the given name, using
+// the default parameters for any that are configurable
+func LossByName(name string) (Loss, error) {
+	switch name {
+	case "binary_cross_entropy":
+		return &BinaryCrossEntropyLoss{}, nil
+	case "categorical_cross_entropy":
+		return &CategoricalCrossEntropyLoss{}, nil
+	case "hinge":
+		return &HingeLoss{}, nil
+	case "huber":
+		return NewHuberLoss(1.0), nil
+	case "mean_absolute":
+		return &MeanAbsoluteLoss{}, nil
+	case "mean_squared":
+		return &MeanSquaredLoss{}, nil
+	case "negative_log_likelihood":
+		return &NegativeLogLikelihoodLoss{}, nil
+	}
+
+	return nil, ErrUnknownLoss
+}
+
+// DefaultLoss returns the loss that pairs with the output layer: categorical
+// cross entropy for a softmax, negative log likelihood for a log softmax and
+// the squared error for anything else
+func DefaultLoss(output *NetworkLayer) Loss {
+	switch {
+	case output == nil || output.Softmax == nil:
+		return &MeanSquaredLoss{}
+	case output.Softmax.Log:
+		return &NegativeLogLikelihoodLoss{}
+	}
+
+	return &CategoricalCrossEntropyLoss{}
+}
+
+// LayerLoss returns the total loss of the layer's potentials against the
+// expected values
+func LayerLoss(loss Loss, expected [][]float64, layer *NetworkLayer) float64 {
+	total := 0.0
+	layer.EachNeuronWithIndex(func(n *Neuron, row, column int) {
+		total += loss.Value(expected[row][column], n.Potential)
+	})
+
+	return total
+}
+
+// clampProbability keeps a probability away from 0 and 1
+func clampProbability(p float64) float64 {
+	return math.Min(math.Max(p, lossEpsilon), 1-lossEpsilon)
+}
+
+// BinaryCrossEntropyLoss is the cross entropy of independent yes or no
+// outputs, which are expected to be probabilities in (0, 1)
+type BinaryCrossEntropyLoss struct{}
+
+// Gradient returns the slope of the binary cross entropy
+func (l *BinaryCrossEntropyLoss) Gradient(expected, actual float64) float64 {
+	p := clampProbability(actual)
+	return (p - expected) / (p * (1 - p))
+}
+
+// Name returns the registered name of the loss
+func (l *BinaryCrossEntropyLoss) Name() string {
+	return "binary_cross_entropy"
+}
+
+// Value returns the binary cross entropy
+func (l *BinaryCrossEntropyLoss) Value(expected, actual float64) float64 {
+	p := clampProbability(actual)
+	return -(expected*math.Log(p) + (1-expected)*math.Log(1-p))
+}
+
+// CategoricalCrossEntropyLoss is the cross entropy of a probability
+// distribution over the whole output layer against the expected distribution
+type CategoricalCrossEntropyLoss struct{}
+
+// Gradient returns the slope of the categorical cross entropy
+func (l *CategoricalCrossEntropyLoss) Gradient(expected, actual float64) float64 {
+	return -expected / clampProbability(actual)
+}
+
+// Name returns the registered name of the loss
+func (l *CategoricalCrossEntropyLoss) Name() string {
+	return "categorical_cross_entropy"
+}
+
+// Value returns the categorical cross entropy
+func (l *CategoricalCrossEntropyLoss) Value(expected, actual float64) float64 {
+	return -expected * math.Log(clampProbability(actual))
+}
+
+// HingeLoss is the maximum margin loss max(0, 1 - expected * actual). Expected
+// values are class labels of 1 or -1, and anything not positive counts as -1
+type HingeLoss struct{}
+
+// Gradient returns the slope of the hinge loss
+func (l *HingeLoss) Gradient(expected, actual float64) float64 {
+	label := hingeLabel(expected)
+	if label*actual < 1 {
+		return -label
+	}
+
+	return 0
+}
+
+// Name returns the registered name of the loss
+func (l *HingeLoss) Name() string {
+	return "hinge"
+}
+
+// Value returns the hinge loss
+func (l *HingeLoss) Value(expected, actual float64) float64 {
+	return math.Max(0, 1-hingeLabel(expected)*actual)
+}
+
+func hingeLabel(expected float64) float64 {
+	if expected > 0 {
+		return 1
+	}
+
+	return -1
+}
+
+// HuberLoss is quadratic for errors up to Delta and linear beyond it, so it's
+// less sensitive to outliers than the squared error
+type HuberLoss struct {
+	Delta float64 `json:"delta"`
+}
+
+// NewHuberLoss creates a new Huber loss that turns linear past delta
+func NewHuberLoss(delta float64) *HuberLoss {
+	return &HuberLoss{Delta: delta}
+}
+
+// Gradient returns the slope of the Huber loss
+func (l *HuberLoss) Gradient(expected, actual float64) float64 {
+	diff := actual - expected
+	if math.Abs(diff) <= l.Delta {
+		return diff
+	}
+
+	return l.Delta * math.Copysign(1, diff)
+}
+
+// Name returns the registered name of the loss
+func (l *HuberLoss) Name() string {
+	return "huber"
+}
+
+// Value returns the Huber loss
+func (l *HuberLoss) Value(expected, actual float64) float64 {
+	diff := math.Abs(actual - expected)
+	if diff <= l.Delta {
+		return 0.5 * diff * diff
+	}
+
+	return l.Delta * (diff - 0.5*l.Delta)
+}
+
+// MeanAbsoluteLoss is the absolute error |actual - expected|
+type MeanAbsoluteLoss struct{}
+
+// Gradient returns the slope of the absolute error
+func (l *MeanAbsoluteLoss) Gradient(expected, actual float64) float64 {
+	switch {
+	case actual > expected:
+		return 1
+	case actual < expected:
+		return -1
+	}
+
+	return 0
+}
+
+// Name returns the registered name of the loss
+func (l *MeanAbsoluteLoss) Name() string {
+	return "mean_absolute"
+}
+
+// Value returns the absolute error
+func (l *MeanAbsoluteLoss) Value(expected, actual float64) float64 {
+	return math.Abs(actual - expected)
+}
+
+// MeanSquaredLoss is the squared error 0.5 * (expected - actual)^2, the same
+// error DefaultEvaluator.MeanSquaredError calculates
+type MeanSquaredLoss struct{}
+
+// Gradient returns the slope of the squared error, which is the linear error
+func (l *MeanSquaredLoss) Gradient(expected, actual float64) float64 {
+	return Evaluator.LinearError(expected, actual)
+}
+
+// Name returns the registered name of the loss
+func (l *MeanSquaredLoss) Name() string {
+	return "mean_squared"
+}
+
+// Value returns the squared error
+func (l *MeanSquaredLoss) Value(expected, actual float64) float64 {
+	return Evaluator.MeanSquaredError(expected, actual)
+}
+
+// NegativeLogLikelihoodLoss is the categorical cross entropy of outputs that
+// are already log probabilities, such as those of a log softmax
+type NegativeLogLikelihoodLoss struct{}
+
+// Gradient returns the slope of the negative log likelihood
+func (l *NegativeLogLikelihoodLoss) Gradient(expected, actual float64) float64 {
+	return -expected
+}
+
+// Name returns the registered name of the loss
+func (l *NegativeLogLikelihoodLoss) Name() string {
+	return "negative_log_likelihood"
+}
+
+// Value returns the negative log likelihood
+func (l *NegativeLogLikelihoodLoss) Value(expected, actual float64) float64 {
+	return -expected * actual
+}
@@ -0,0 +1,145 @@
+package ann
+
+import (
+	"math"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestLosses(suite *testing.T) {
+	names := []string{"binary_cross_entropy", "categorical_cross_entropy", "hinge",
+		"huber", "mean_absolute", "mean_squared", "negative_log_likelihood"}
+
+	this.Before(suite, func() {
+		InhibitoryNeuronDensity = 0.0
+	})
+
+	this.Should("Look up each built-in loss by name", suite,
+		func() {
+			for _, name := range names {
+				loss, err := LossByName(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(loss.Name()).To(Equal(name))
+			}
+
+			_, err := LossByName("nope")
+			Expect(err).To(Equal(ErrUnknownLoss))
+		})
+
+	this.Should("Provide gradients that match a finite difference estimate", suite,
+		func() {
+			epsilon := 1e-6
+			for _, name := range names {
+				loss, _ := LossByName(name)
+
+				// Stay clear of the kinks in the absolute, hinge and Huber losses
+				for _, expected := range []float64{0.0, 0.3, 1.0} {
+					for _, actual := range []float64{0.12, 0.45, 0.87} {
+						numeric := (loss.Value(expected, actual+epsilon) -
+							loss.Value(expected, actual-epsilon)) / (2 * epsilon)
+						Expect(loss.Gradient(expected, actual)).To(BeNumerically("~", numeric, 1e-5))
+					}
+				}
+			}
+		})
+
+	this.Should("Calculate the expected values", suite,
+		func() {
+			Expect((&MeanSquaredLoss{}).Value(1, 3)).To(Equal(2.0))
+			Expect((&MeanAbsoluteLoss{}).Value(1, 3)).To(Equal(2.0))
+			Expect(NewHuberLoss(1).Value(1, 3)).To(Equal(1.5))
+			Expect(NewHuberLoss(1).Value(1, 1.5)).To(Equal(0.125))
+			Expect((&HingeLoss{}).Value(1, 2)).To(Equal(0.0))
+			Expect((&HingeLoss{}).Value(-1, 0.5)).To(Equal(1.5))
+			Expect((&BinaryCrossEntropyLoss{}).Value(1, 0.5)).To(BeNumerically("~", math.Ln2, 1e-12))
+			Expect((&CategoricalCrossEntropyLoss{}).Value(0, 0.5)).To(Equal(0.0))
+
+			// Probabilities of exactly 0 or 1 stay finite
+			Expect(math.IsInf((&BinaryCrossEntropyLoss{}).Value(1, 0), 0)).To(BeFalse())
+			Expect(math.IsInf((&CategoricalCrossEntropyLoss{}).Gradient(1, 0), 0)).To(BeFalse())
+		})
+
+	this.Should("Backpropagate the gradient of the configured loss", suite,
+		func() {
+			input := [][]float64{[]float64{0.2, 0.8}, []float64{0.6, 0.1}}
+			expected := [][]float64{[]float64{1.0, 0.0}}
+
+			network := NewNeuralNetwork(0, 0, 0)
+			network.AddConfiguredLayer(LayerConfiguration{Width: 2, Height: 2, Activation: &Identity{}})
+			network.AddConfiguredLayer(LayerConfiguration{Width: 3, Height: 3, Activation: &Tanh{}})
+			network.AddConfiguredLayer(LayerConfiguration{Width: 1, Height: 2, Activation: &Logistic{}})
+
+			engine := NewGradientEngine(0.5)
+			totalLoss := func(loss Loss) float64 {
+				engine.Run(input, network)
+				return LayerLoss(loss, expected, network.GetOutput())
+			}
+
+			for _, name := range []string{"binary_cross_entropy", "huber", "hinge"} {
+				loss, _ := LossByName(name)
+				engine.Run(input, network)
+				grads := NewGradients()
+				Expect(engine.CalculateGradientsWithLoss(expected, network, loss, grads)).To(Succeed())
+
+				epsilon := 1e-6
+				for conn, grad := range grads.Weights {
+					orig := conn.Weight
+					conn.Weight = orig + epsilon
+					plus := totalLoss(loss)
+					conn.Weight = orig - epsilon
+					minus := totalLoss(loss)
+					conn.Weight = orig
+
+					Expect(grad).To(BeNumerically("~", (plus-minus)/(2*epsilon), 1e-6))
+				}
+			}
+		})
+
+	this.Should("Train against the configured loss", suite,
+		func() {
+			network := NewNeuralNetwork(0, 0, 0)
+			network.AddConfiguredLayer(LayerConfiguration{Width: 2, Height: 1, Activation: &Identity{}})
+			network.AddConfiguredLayer(LayerConfiguration{Width: 1, Height: 1, Activation: &Logistic{}})
+
+			inputs := []*InputConfiguration{
+				&InputConfiguration{
+					Values:   [][]float64{[]float64{1}, []float64{0}},
+					Expected: [][]float64{[]float64{1}},
+					Weight:   1,
+				},
+				&InputConfiguration{
+					Values:   [][]float64{[]float64{0}, []float64{1}},
+					Expected: [][]float64{[]float64{0}},
+					Weight:   1,
+				},
+			}
+
+			loss := &BinaryCrossEntropyLoss{}
+			config := &TrainingConfiguration{
+				BatchSize: FullBatch,
+				Inputs:    inputs,
+				Loss:      loss,
+				Network:   network,
+			}
+
+			engine := NewGradientEngine(0.5)
+			before := 0.0
+			for _, input := range inputs {
+				engine.Run(input.Values, network)
+				before += LayerLoss(loss, input.Expected, network.GetOutput())
+			}
+
+			engine.Train(200, config)
+
+			after := 0.0
+			for _, input := range inputs {
+				engine.Run(input.Values, network)
+				after += LayerLoss(loss, input.Expected, network.GetOutput())
+			}
+
+			Expect(after).To(BeNumerically("<", before))
+			Expect(after).To(BeNumerically("<", 0.2))
+		})
+}
@@ -0,0 +1,451 @@
+package ann
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	// NetworkBinaryVersion is the version of the binary network format written
+	// by SaveBinary
+	NetworkBinaryVersion = 1
+)
+
+const (
+	// PrecisionFloat64 stores weights and biases as 64 bit floats
+	PrecisionFloat64 = iota
+
+	// PrecisionFloat32 stores weights and biases as 32 bit floats, halving the
+	// size of the weight blocks at the cost of precision
+	PrecisionFloat32 = iota
+)
+
+const (
+	// flagFloat32 marks files whose float blocks are stored in 32 bits
+	flagFloat32 = 1 << 0
+
+	// binaryHeaderSize is the size of the magic bytes, version and flags
+	binaryHeaderSize = 8
+
+	// Each layer's softmax is stored as a set of flags
+	softmaxEnabled = 1 << 0
+	softmaxLog     = 1 << 1
+	softmaxPerRow  = 1 << 2
+)
+
+var (
+	// networkBinaryMagic are the bytes every binary network file starts with
+	networkBinaryMagic = [4]byte{'A', 'N', 'N', 'B'}
+
+	// ErrBadMagic is the error for when a file isn't a binary network at all
+	ErrBadMagic = errors.New("Not a binary network file")
+
+	// ErrChecksumMismatch is the error for when a binary network's checksum
+	// doesn't match its contents, usually because the file is corrupted
+	ErrChecksumMismatch = errors.New("Binary network checksum mismatch, the file is corrupted")
+
+	// ErrTruncated is the error for when a binary network ends unexpectedly
+	ErrTruncated = errors.New("Binary network file is truncated")
+
+	// networkBinaryDecoders maps each format version to its decoder, so files
+	// written by older versions keep loading as the format evolves
+	networkBinaryDecoders = map[uint16]func(r *binaryReader) (*flatNetwork, error){
+		1: decodeNetworkBinaryV1,
+	}
+)
+
+// SaveBinary writes the network to the writer in the compact binary format.
+// The layout is, in little endian order:
+//
+//	magic "ANNB", version uint16, flags uint16
+//	current time step, potential step, potential threshold, time step size
+//	seed int64
+//	layer count uint32, then for each layer its width uint32, height uint32,
+//	  activation name, JSON encoded activation parameters, softmax flags
+//	  uint8, regularization L1, L2 and max norm and dropout and DropConnect
+//	  rates, all as 64 bit floats
+//	neuron types, one uint8 per neuron across all layers
+//	bias block, one float per neuron
+//	connection count uint32, then for each connection its source and target
+//	  neuron indices uint32 and connection count int32
+//	weight block, one float per connection
+//	incoming connection indices for each neuron
+//	CRC-32 (IEEE) of everything before it
+//
+// Floats in the bias and weight blocks are 64 bits unless PrecisionFloat32 is
+// used. Neurons are indexed in layer, row, column order
+func (n *NeuralNetwork) SaveBinary(w io.Writer, precision int) error {
+	src, err := n.flatten()
+	if err != nil {
+		return err
+	}
+
+	out := &binaryWriter{buf: &bytes.Buffer{}, float32: precision == PrecisionFloat32}
+	flags := uint16(0)
+	if out.float32 {
+		flags |= flagFloat32
+	}
+
+	out.buf.Write(networkBinaryMagic[:])
+	out.write(uint16(NetworkBinaryVersion))
+	out.write(flags)
+	out.write(src.CurrentTimeStep)
+	out.write(src.PotentialStep)
+	out.write(src.PotentialThreshold)
+	out.write(src.TimeStepSize)
+	out.write(src.Seed)
+
+	// Neurons are referenced by their flat index, so track where each layer
+	// starts
+	offsets := make([]int, len(src.Layers))
+	total := 0
+	out.write(uint32(len(src.Layers)))
+	for i, layer := range src.Layers {
+		offsets[i] = total
+		total += layer.Width * layer.Height
+
+		out.write(uint32(layer.Width))
+		out.write(uint32(layer.Height))
+		if layer.Activation != nil {
+			out.writeBytes([]byte(layer.Activation.Name))
+			out.writeBytes(layer.Activation.Params)
+		} else {
+			out.writeBytes(nil)
+			out.writeBytes(nil)
+		}
+
+		softmax := uint8(0)
+		if layer.Softmax != nil {
+			softmax |= softmaxEnabled
+			if layer.Softmax.Log {
+				softmax |= softmaxLog
+			}
+			if layer.Softmax.PerRow {
+				softmax |= softmaxPerRow
+			}
+		}
+		out.write(softmax)
+
+		regularization := Regularization{}
+		if layer.Regularization != nil {
+			regularization = *layer.Regularization
+		}
+		out.write(regularization.L1)
+		out.write(regularization.L2)
+		out.write(regularization.MaxNorm)
+		out.write(layer.Dropout)
+		out.write(layer.DropConnect)
+	}
+
+	index := func(pos [3]int) uint32 {
+		return uint32(offsets[pos[0]] + pos[1]*src.Layers[pos[0]].Height + pos[2])
+	}
+
+	eachNeuron := func(do func(neuron flatNeuron)) {
+		for _, layer := range src.Layers {
+			for _, row := range layer.Neurons {
+				for _, neuron := range row {
+					do(neuron)
+				}
+			}
+		}
+	}
+
+	eachNeuron(func(neuron flatNeuron) {
+		out.write(uint8(neuron.Type))
+	})
+	eachNeuron(func(neuron flatNeuron) {
+		out.writeFloat(neuron.Bias)
+	})
+
+	out.write(uint32(len(src.Connections)))
+	for _, conn := range src.Connections {
+		out.write(index(conn.Source))
+		out.write(index(conn.Target))
+		out.write(int32(conn.Connections))
+	}
+	for _, conn := range src.Connections {
+		out.writeFloat(conn.Weight)
+	}
+
+	eachNeuron(func(neuron flatNeuron) {
+		out.write(uint32(len(neuron.Incoming)))
+		for _, i := range neuron.Incoming {
+			out.write(uint32(i))
+		}
+	})
+
+	out.write(crc32.ChecksumIEEE(out.buf.Bytes()))
+	_, err = w.Write(out.buf.Bytes())
+	return err
+}
+
+// LoadBinary replaces the network with the binary network read from the
+// reader. Corrupted files are rejected before anything is rebuilt
+func (n *NeuralNetwork) LoadBinary(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if len(data) < binaryHeaderSize+crc32.Size {
+		return ErrTruncated
+	}
+
+	if !bytes.Equal(data[:4], networkBinaryMagic[:]) {
+		return ErrBadMagic
+	}
+
+	body, sum := data[:len(data)-crc32.Size], data[len(data)-crc32.Size:]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(sum) {
+		return ErrChecksumMismatch
+	}
+
+	version := binary.LittleEndian.Uint16(data[4:6])
+	decode, ok := networkBinaryDecoders[version]
+	if !ok {
+		return fmt.Errorf("Unsupported binary network version %d", version)
+	}
+
+	flags := binary.LittleEndian.Uint16(data[6:8])
+	in := &binaryReader{
+		r:       bytes.NewReader(body[binaryHeaderSize:]),
+		float32: flags&flagFloat32 != 0,
+	}
+
+	src, err := decode(in)
+	if err != nil {
+		return err
+	}
+
+	return n.unflatten(src)
+}
+
+// decodeNetworkBinaryV1 decodes the body of a version 1 binary network
+func decodeNetworkBinaryV1(in *binaryReader) (*flatNetwork, error) {
+	src := &flatNetwork{}
+	in.read(&src.CurrentTimeStep)
+	in.read(&src.PotentialStep)
+	in.read(&src.PotentialThreshold)
+	in.read(&src.TimeStepSize)
+	in.read(&src.Seed)
+
+	var layerCount uint32
+	in.read(&layerCount)
+	if in.err != nil {
+		return nil, in.err
+	}
+
+	// Everything gets pointed at by index, so make sure the shapes are sane
+	// before allocating anything
+	total := 0
+	src.Layers = make([]flatLayer, 0)
+	positions := make([][3]int, 0)
+	for i := 0; i < int(layerCount) && in.err == nil; i++ {
+		var width, height uint32
+		in.read(&width)
+		in.read(&height)
+		name := in.readBytes()
+		params := in.readBytes()
+
+		var softmax uint8
+		in.read(&softmax)
+
+		var regularization Regularization
+		in.read(&regularization.L1)
+		in.read(&regularization.L2)
+		in.read(&regularization.MaxNorm)
+
+		var dropout, dropConnect float64
+		in.read(&dropout)
+		in.read(&dropConnect)
+		if in.err != nil {
+			break
+		}
+
+		if uint64(width)*uint64(height) > uint64(in.r.Len()) {
+			return nil, ErrTruncated
+		}
+
+		layer := flatLayer{
+			Width:       int(width),
+			Height:      int(height),
+			Dropout:     dropout,
+			DropConnect: dropConnect,
+			Neurons:     make([][]flatNeuron, width),
+		}
+		if len(name) > 0 {
+			layer.Activation = &flatActivation{Name: string(name), Params: params}
+		}
+		if softmax&softmaxEnabled != 0 {
+			layer.Softmax = &Softmax{
+				Log:    softmax&softmaxLog != 0,
+				PerRow: softmax&softmaxPerRow != 0,
+			}
+		}
+		if regularization != (Regularization{}) {
+			layer.Regularization = &regularization
+		}
+
+		for row := range layer.Neurons {
+			layer.Neurons[row] = make([]flatNeuron, height)
+			for column := range layer.Neurons[row] {
+				positions = append(positions, [3]int{i, row, column})
+			}
+		}
+
+		total += layer.Width * layer.Height
+		src.Layers = append(src.Layers, layer)
+	}
+
+	neuron := func(index int) *flatNeuron {
+		pos := positions[index]
+		return &src.Layers[pos[0]].Neurons[pos[1]][pos[2]]
+	}
+
+	for i := 0; i < total && in.err == nil; i++ {
+		var nType uint8
+		in.read(&nType)
+		neuron(i).Type = int(nType)
+	}
+	for i := 0; i < total && in.err == nil; i++ {
+		neuron(i).Bias = in.readFloat()
+	}
+
+	var connCount uint32
+	in.read(&connCount)
+	if in.err != nil {
+		return nil, in.err
+	}
+
+	if uint64(connCount) > uint64(in.r.Len()) {
+		return nil, ErrTruncated
+	}
+
+	src.Connections = make([]flatConnection, connCount)
+	for i := range src.Connections {
+		var source, target uint32
+		var count int32
+		in.read(&source)
+		in.read(&target)
+		in.read(&count)
+		if in.err != nil {
+			return nil, in.err
+		}
+
+		if int(source) >= total || int(target) >= total {
+			return nil, ErrInvalidNetwork
+		}
+
+		src.Connections[i] = flatConnection{
+			Source:      positions[source],
+			Target:      positions[target],
+			Connections: int(count),
+		}
+	}
+	for i := range src.Connections {
+		src.Connections[i].Weight = in.readFloat()
+	}
+
+	for i := 0; i < total && in.err == nil; i++ {
+		var count uint32
+		in.read(&count)
+		if uint64(count) > uint64(len(src.Connections)) {
+			return nil, ErrInvalidNetwork
+		}
+
+		incoming := make([]int, count)
+		for j := range incoming {
+			var index uint32
+			in.read(&index)
+			incoming[j] = int(index)
+		}
+		neuron(i).Incoming = incoming
+	}
+
+	if in.err != nil {
+		return nil, in.err
+	}
+
+	return src, nil
+}
+
+// binaryWriter writes little endian values to a buffer
+type binaryWriter struct {
+	buf     *bytes.Buffer
+	float32 bool
+}
+
+func (b *binaryWriter) write(val interface{}) {
+	// Writes to a bytes.Buffer can't fail
+	binary.Write(b.buf, binary.LittleEndian, val)
+}
+
+func (b *binaryWriter) writeBytes(val []byte) {
+	b.write(uint32(len(val)))
+	b.buf.Write(val)
+}
+
+func (b *binaryWriter) writeFloat(val float64) {
+	if b.float32 {
+		b.write(float32(val))
+	} else {
+		b.write(val)
+	}
+}
+
+// binaryReader reads little endian values, holding on to the first error so
+// callers can check once after a batch of reads
+type binaryReader struct {
+	r       *bytes.Reader
+	float32 bool
+	err     error
+}
+
+func (b *binaryReader) read(val interface{}) {
+	if b.err != nil {
+		return
+	}
+
+	if err := binary.Read(b.r, binary.LittleEndian, val); err != nil {
+		b.err = ErrTruncated
+	}
+}
+
+func (b *binaryReader) readBytes() []byte {
+	var size uint32
+	b.read(&size)
+	if b.err != nil {
+		return nil
+	}
+
+	if uint64(size) > uint64(b.r.Len()) {
+		b.err = ErrTruncated
+		return nil
+	}
+
+	val := make([]byte, size)
+	b.r.Read(val)
+	if size == 0 {
+		return nil
+	}
+
+	return val
+}
+
+func (b *binaryReader) readFloat() float64 {
+	if b.float32 {
+		var val float32
+		b.read(&val)
+		return float64(val)
+	}
+
+	var val float64
+	b.read(&val)
+	return val
+}
@@ -0,0 +1,174 @@
+package ann
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestNetworkBinary(suite *testing.T) {
+	input := [][]float64{
+		[]float64{0.1, 0.9, 1.03, 0.2},
+		[]float64{0.51, 0.5, 0.5, 0.7},
+		[]float64{0.9, 0.85, 0.01, 0.3},
+	}
+
+	newNetwork := func() *NeuralNetwork {
+		network := newTestNetwork(3,
+			LayerConfiguration{Width: 3, Height: 4, Activation: &Identity{}},
+			LayerConfiguration{Width: 5, Height: 2, InhibitoryDensity: 0.3, Activation: NewELU(0.5)},
+			LayerConfiguration{Width: 2, Height: 3})
+		randomizeBiases(network)
+
+		return network
+	}
+
+	outputs := func(network *NeuralNetwork) [][]float64 {
+		network.Run(input)
+		out := make([][]float64, network.GetOutput().Width())
+		network.GetOutput().EachNeuronWithIndex(func(n *Neuron, row, column int) {
+			out[row] = append(out[row], n.Potential)
+		})
+
+		return out
+	}
+
+	this.Should("Produce identical outputs after a round trip", suite,
+		func() {
+			network := newNetwork()
+			buf := &bytes.Buffer{}
+			Expect(network.SaveBinary(buf, PrecisionFloat64)).To(Succeed())
+			Expect(buf.Bytes()[:4]).To(Equal([]byte("ANNB")))
+
+			loaded := &NeuralNetwork{}
+			Expect(loaded.LoadBinary(buf)).To(Succeed())
+			Expect(outputs(loaded)).To(Equal(outputs(network)))
+			Expect(loaded.Layers[1].Activation).To(Equal(NewELU(0.5)))
+			Expect(loaded.GetOutput().Activation).To(BeNil())
+		})
+
+	this.Should("Keep each layer's softmax", suite,
+		func() {
+			network := newNetwork()
+			network.GetOutput().SetActivation(&Identity{})
+			network.GetOutput().Softmax = &Softmax{Log: true, PerRow: true}
+
+			buf := &bytes.Buffer{}
+			Expect(network.SaveBinary(buf, PrecisionFloat64)).To(Succeed())
+
+			loaded := &NeuralNetwork{}
+			Expect(loaded.LoadBinary(buf)).To(Succeed())
+			Expect(loaded.GetOutput().Softmax).To(Equal(&Softmax{Log: true, PerRow: true}))
+			Expect(loaded.Layers[1].Softmax).To(BeNil())
+			Expect(outputs(loaded)).To(Equal(outputs(network)))
+		})
+
+	this.Should("Keep each layer's regularization and dropout rates", suite,
+		func() {
+			network := newNetwork()
+			network.Layers[1].Regularization = &Regularization{L1: 0.001, L2: 0.01, MaxNorm: 3}
+			network.Layers[1].Dropout = 0.2
+			network.GetOutput().DropConnect = 0.1
+
+			buf := &bytes.Buffer{}
+			Expect(network.SaveBinary(buf, PrecisionFloat32)).To(Succeed())
+
+			loaded := &NeuralNetwork{}
+			Expect(loaded.LoadBinary(buf)).To(Succeed())
+			Expect(loaded.Layers[1].Regularization).To(Equal(&Regularization{L1: 0.001, L2: 0.01, MaxNorm: 3}))
+			Expect(loaded.GetOutput().Regularization).To(BeNil())
+			Expect(loaded.Layers[1].Dropout).To(Equal(0.2))
+			Expect(loaded.GetOutput().DropConnect).To(Equal(0.1))
+		})
+
+	this.Should("Store smaller files with float32 weights", suite,
+		func() {
+			network := newNetwork()
+			full := &bytes.Buffer{}
+			half := &bytes.Buffer{}
+			network.SaveBinary(full, PrecisionFloat64)
+			network.SaveBinary(half, PrecisionFloat32)
+			Expect(half.Len()).To(BeNumerically("<", full.Len()))
+
+			loaded := &NeuralNetwork{}
+			Expect(loaded.LoadBinary(half)).To(Succeed())
+
+			expected := outputs(network)
+			for i, row := range outputs(loaded) {
+				for j, val := range row {
+					Expect(val).To(BeNumerically("~", expected[i][j], 1e-5))
+				}
+			}
+		})
+
+	this.Should("Reject corrupted files", suite,
+		func() {
+			buf := &bytes.Buffer{}
+			newNetwork().SaveBinary(buf, PrecisionFloat64)
+			data := buf.Bytes()
+			data[len(data)/2] ^= 0xff
+
+			err := (&NeuralNetwork{}).LoadBinary(bytes.NewReader(data))
+			Expect(err).To(Equal(ErrChecksumMismatch))
+		})
+
+	this.Should("Reject files that aren't binary networks", suite,
+		func() {
+			err := (&NeuralNetwork{}).LoadBinary(bytes.NewReader([]byte("{\"version\":1}")))
+			Expect(err).To(Equal(ErrBadMagic))
+
+			err = (&NeuralNetwork{}).LoadBinary(bytes.NewReader([]byte("AN")))
+			Expect(err).To(Equal(ErrTruncated))
+		})
+
+	this.Should("Reject unknown format versions", suite,
+		func() {
+			withVersion := func(version uint16) []byte {
+				buf := &bytes.Buffer{}
+				newNetwork().SaveBinary(buf, PrecisionFloat64)
+				data := buf.Bytes()
+				binary.LittleEndian.PutUint16(data[4:6], version)
+				binary.LittleEndian.PutUint32(data[len(data)-4:],
+					crc32.ChecksumIEEE(data[:len(data)-4]))
+
+				return data
+			}
+
+			for _, version := range []uint16{0, 2, 99} {
+				err := (&NeuralNetwork{}).LoadBinary(bytes.NewReader(withVersion(version)))
+				Expect(err).To(MatchError(fmt.Sprintf("Unsupported binary network version %d", version)))
+			}
+
+			// Versions are looked up in the decoder table, so registering one makes
+			// its files load
+			networkBinaryDecoders[2] = decodeNetworkBinaryV1
+			defer delete(networkBinaryDecoders, 2)
+			Expect((&NeuralNetwork{}).LoadBinary(bytes.NewReader(withVersion(2)))).To(Succeed())
+
+			for _, version := range []int{0, 2} {
+				data := fmt.Sprintf(`{"version":%d,"layers":[{"width":1,"height":1,`+
+					`"neurons":[[{"bias":0,"type":0}]]}],"connections":[]}`, version)
+				err := (&NeuralNetwork{}).LoadJSON(strings.NewReader(data))
+				Expect(err).To(MatchError(fmt.Sprintf("Unsupported JSON network version %d", version)))
+			}
+		})
+
+	this.Should("Reject truncated bodies with a valid checksum", suite,
+		func() {
+			buf := &bytes.Buffer{}
+			newNetwork().SaveBinary(buf, PrecisionFloat64)
+			data := append([]byte{}, buf.Bytes()[:40]...)
+			data = append(data, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint32(data[len(data)-4:],
+				crc32.ChecksumIEEE(data[:len(data)-4]))
+
+			err := (&NeuralNetwork{}).LoadBinary(bytes.NewReader(data))
+			Expect(err).To(Equal(ErrTruncated))
+		})
+}
@@ -1,4 +1,6 @@
-package main
+package ann
+
+import "math/rand"
 
 // NetworkConfiguration hm...
 type NetworkConfiguration interface {
@@ -9,6 +11,8 @@ type NetworkConfiguration interface {
 	GetInput() *NetworkLayer
 	GetLayers() []*NetworkLayer
 	GetOutput() *NetworkLayer
+	GetRand() *rand.Rand
+	GetSeed() int64
 	Print()
 	Run([][]float64) error
 	SetDebug(debug bool)
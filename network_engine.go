@@ -1,7 +1,7 @@
-package main
+package ann
 
 // NetworkEngine the general interface for network engines
 type NetworkEngine interface {
 	Run(input [][]float64, network NetworkConfiguration) error
-	Train(iterations int, config *TrainingConfiguration)
+	Train(iterations int, config *TrainingConfiguration) (*History, error)
 }
@@ -0,0 +1,48 @@
+package ann
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	// NetworkJSONVersion is the version of the JSON network format written by
+	// SaveJSON
+	NetworkJSONVersion = 1
+)
+
+// SaveJSON writes the network to the writer as JSON
+func (n *NeuralNetwork) SaveJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(n)
+}
+
+// LoadJSON replaces the network with the JSON network read from the reader
+func (n *NeuralNetwork) LoadJSON(r io.Reader) error {
+	return json.NewDecoder(r).Decode(n)
+}
+
+// MarshalJSON encodes the network into its flat JSON representation
+func (n *NeuralNetwork) MarshalJSON() ([]byte, error) {
+	src, err := n.flatten()
+	if err != nil {
+		return nil, err
+	}
+
+	src.Version = NetworkJSONVersion
+	return json.Marshal(src)
+}
+
+// UnmarshalJSON rebuilds the network from its flat JSON representation
+func (n *NeuralNetwork) UnmarshalJSON(data []byte) error {
+	var src flatNetwork
+	if err := json.Unmarshal(data, &src); err != nil {
+		return err
+	}
+
+	if src.Version != NetworkJSONVersion {
+		return fmt.Errorf("Unsupported JSON network version %d", src.Version)
+	}
+
+	return n.unflatten(&src)
+}
@@ -0,0 +1,104 @@
+package ann
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestNetworkJSON(suite *testing.T) {
+	newNetwork := func() *NeuralNetwork {
+		network := newTestNetwork(3,
+			LayerConfiguration{Width: 3, Height: 4, Activation: &Identity{}},
+			LayerConfiguration{Width: 5, Height: 2, InhibitoryDensity: 0.3, Activation: NewLeakyReLU(0.2),
+				Regularization: &Regularization{L2: 0.01, MaxNorm: 2}, Dropout: 0.5, DropConnect: 0.25},
+			LayerConfiguration{Width: 2, Height: 2, Activation: &Tanh{}, Softmax: &Softmax{PerRow: true}})
+		randomizeBiases(network)
+		network.GetInput().Neurons[0][0].Out[3].Strengthen()
+
+		return network
+	}
+
+	this.Should("Marshal a network without recursing through its connections", suite,
+		func() {
+			network := newNetwork()
+			data, err := json.Marshal(network)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(data)).To(BeNumerically(">", 0))
+		})
+
+	this.Should("Produce identical outputs after a round trip", suite,
+		func() {
+			network := newNetwork()
+			buf := &bytes.Buffer{}
+			Expect(network.SaveJSON(buf)).To(Succeed())
+
+			loaded := &NeuralNetwork{}
+			Expect(loaded.LoadJSON(buf)).To(Succeed())
+			Expect(loaded.GetOutput().Softmax).To(Equal(&Softmax{PerRow: true}))
+
+			for i := 0; i < 10; i++ {
+				input := newTestInput(network, 1)
+				network.Run(input)
+				loaded.Run(input)
+
+				network.GetOutput().EachNeuronWithIndex(func(n *Neuron, row, column int) {
+					Expect(loaded.GetOutput().Neurons[row][column].Potential).To(Equal(n.Potential))
+				})
+			}
+		})
+
+	this.Should("Rebuild the exact graph on load", suite,
+		func() {
+			network := newNetwork()
+			buf := &bytes.Buffer{}
+			network.SaveJSON(buf)
+
+			loaded := &NeuralNetwork{}
+			loaded.LoadJSON(buf)
+
+			Expect(loaded.GetDepth()).To(Equal(network.GetDepth()))
+			Expect(loaded.PotentialStep).To(Equal(network.PotentialStep))
+			Expect(loaded.TimeStepSize).To(Equal(network.TimeStepSize))
+			Expect(loaded.Layers[1].Activation).To(Equal(NewLeakyReLU(0.2)))
+			Expect(loaded.Layers[1].Regularization).To(Equal(&Regularization{L2: 0.01, MaxNorm: 2}))
+			Expect(loaded.Layers[2].Regularization).To(BeNil())
+			Expect(loaded.Layers[1].Dropout).To(Equal(0.5))
+			Expect(loaded.Layers[1].DropConnect).To(Equal(0.25))
+
+			for i, layer := range network.GetLayers() {
+				layer.EachNeuronWithIndex(func(n *Neuron, row, column int) {
+					other := loaded.Layers[i].Neurons[row][column]
+					Expect(other.Bias).To(Equal(n.Bias))
+					Expect(other.Type).To(Equal(n.Type))
+					Expect(other.Activation).To(Equal(n.Activation))
+					Expect(len(other.In)).To(Equal(len(n.In)))
+					Expect(len(other.Out)).To(Equal(len(n.Out)))
+
+					for j, conn := range n.Out {
+						Expect(other.Out[j].Weight).To(Equal(conn.Weight))
+						Expect(other.Out[j].Connections).To(Equal(conn.Connections))
+						Expect(other.Out[j].Source).To(BeIdenticalTo(other))
+					}
+
+					for j, conn := range n.In {
+						Expect(other.In[j].Weight).To(Equal(conn.Weight))
+						Expect(other.In[j].Target).To(BeIdenticalTo(other))
+					}
+				})
+			}
+		})
+
+	this.Should("Reject connections that refer to missing neurons", suite,
+		func() {
+			data := `{"version":1,"layers":[{"width":1,"height":1,"neurons":[[{"bias":0,"type":0}]]}],` +
+				`"connections":[{"source":[0,0,0],"target":[3,0,0],"connections":1,"weight":1}]}`
+
+			err := (&NeuralNetwork{}).LoadJSON(strings.NewReader(data))
+			Expect(err).To(Equal(ErrInvalidNetwork))
+		})
+}
@@ -1,4 +1,4 @@
-package main
+package ann
 
 import (
 	"fmt"
@@ -14,19 +14,50 @@ var (
 
 // NetworkLayer an individual, 2D layer of neurons
 type NetworkLayer struct {
-	Neurons [][]*Neuron `json:"neurons"`
+	Activation Activation  `json:"-"`
+	Neurons    [][]*Neuron `json:"neurons"`
+
+	// Dropout is the rate at which the layer's neurons are dropped while
+	// training, and DropConnect the rate for its incoming connections
+	Dropout     float64 `json:"-"`
+	DropConnect float64 `json:"-"`
+
+	// Regularization optionally keeps the layer's incoming weights small while
+	// training
+	Regularization *Regularization `json:"-"`
+
+	// Softmax optionally normalizes the layer's activated potentials
+	Softmax *Softmax `json:"-"`
+
+	// rng is the random source the layer was built with, used to weight the
+	// connections coming in to it
+	rng *rand.Rand
 }
 
-// NewNetworkLayer creates a new network layer of the specified width and height
+// NewNetworkLayer creates a new network layer of the specified width and
+// height, using the InhibitoryNeuronDensity
 func NewNetworkLayer(width, height int) *NetworkLayer {
-	layer := &NetworkLayer{Neurons: make([][]*Neuron, width)}
+	return NewNetworkLayerWithDensity(width, height, InhibitoryNeuronDensity)
+}
+
+// NewNetworkLayerWithDensity creates a new network layer of the specified width
+// and height with the given density of inhibitory neurons
+func NewNetworkLayerWithDensity(width, height int, density float64) *NetworkLayer {
+	return NewNetworkLayerWithRand(width, height, density, globalRand)
+}
+
+// NewNetworkLayerWithRand creates a new network layer of the specified width
+// and height with the given density of inhibitory neurons, drawing from the
+// given random source
+func NewNetworkLayerWithRand(width, height int, density float64, rng *rand.Rand) *NetworkLayer {
+	layer := &NetworkLayer{Neurons: make([][]*Neuron, width), rng: rng}
 
 	for i := range layer.Neurons {
 		layer.Neurons[i] = make([]*Neuron, height)
 
 		for j := 0; j < len(layer.Neurons[i]); j++ {
 			// Create the right density of inhibitory and excitatory neurons
-			if rand.Float64() < InhibitoryNeuronDensity {
+			if rng.Float64() < density {
 				layer.Neurons[i][j] = NewNeuron(TypeInhibitory)
 			} else {
 				layer.Neurons[i][j] = NewNeuron(TypeExcitatory)
@@ -37,6 +68,18 @@ func NewNetworkLayer(width, height int) *NetworkLayer {
 	return layer
 }
 
+// Activate applies each neuron's activation to its current potential, followed
+// by the layer's softmax if it has one
+func (l *NetworkLayer) Activate() {
+	l.EachNeuron(func(n *Neuron) {
+		n.Activate()
+	})
+
+	if l.Softmax != nil {
+		l.Softmax.apply(l)
+	}
+}
+
 // Clear clears the current layer's state back to 0.0
 func (l *NetworkLayer) Clear() {
 	l.EachNeuron(func(n *Neuron) {
@@ -70,11 +113,44 @@ func (l *NetworkLayer) Connect(target *NetworkLayer) {
 		// And connect it to each neuron in the target layer
 		target.EachNeuron(func(tgtNeuron *Neuron) {
 			conn := src.Connect(tgtNeuron)
-			conn.Weight = rand.Float64()
+			conn.Weight = target.random().Float64()
 		})
 	})
 }
 
+// ConnectLocal connects each neuron in the target layer to the neurons in a
+// square window around the matching position of this layer. Positions are
+// scaled between the layers, so layers of different sizes line up, and the
+// window reaches radius neurons in every direction
+func (l *NetworkLayer) ConnectLocal(target *NetworkLayer, radius int) {
+	l.EachNeuronWithIndex(func(src *Neuron, srcRow, srcCol int) {
+		target.EachNeuronWithIndex(func(tgtNeuron *Neuron, tgtRow, tgtCol int) {
+			row := tgtRow * l.Width() / target.Width()
+			col := tgtCol * l.Height() / target.Height()
+
+			if abs(srcRow-row) <= radius && abs(srcCol-col) <= radius {
+				conn := src.Connect(tgtNeuron)
+				conn.Weight = target.random().Float64()
+			}
+		})
+	})
+}
+
+// ConnectOneToOne connects each neuron in this layer to the neuron at the same
+// position in the target layer. Both layers have to be the same size
+func (l *NetworkLayer) ConnectOneToOne(target *NetworkLayer) error {
+	if l.Width() != target.Width() || l.Height() != target.Height() {
+		return ErrArraySizeMismatch
+	}
+
+	l.EachNeuronWithIndex(func(src *Neuron, row, col int) {
+		conn := src.Connect(target.Neurons[row][col])
+		conn.Weight = target.random().Float64()
+	})
+
+	return nil
+}
+
 // Height returns the height of the current layer
 func (l *NetworkLayer) Height() int {
 	return len(l.Neurons[0])
@@ -83,10 +159,16 @@ func (l *NetworkLayer) Height() int {
 // Print prints out the current layer using the field specified. Values are
 // "potential", "total_in", "total_out"
 func (l *NetworkLayer) Print(field string) {
+	l.Fprint(os.Stdout, field)
+}
+
+// Fprint prints out the current layer to the given writer using the field
+// specified, the same way Print does
+func (l *NetworkLayer) Fprint(w io.Writer, field string) {
 	currRow := 0
 	l.EachNeuronWithIndex(func(n *Neuron, row, col int) {
 		if row != currRow {
-			io.WriteString(os.Stdout, "\n")
+			io.WriteString(w, "\n")
 			currRow = row
 		}
 
@@ -104,7 +186,7 @@ func (l *NetworkLayer) Print(field string) {
 			}
 		}
 
-		io.WriteString(os.Stdout, fmt.Sprintf(" %.3f", val))
+		io.WriteString(w, fmt.Sprintf(" %.3f", val))
 	})
 }
 
@@ -118,10 +200,28 @@ func (l *NetworkLayer) PrintErrorOffset(errMap map[*Neuron]*NeuronError) {
 				errMap[neuron].Error, neuron.Potential)
 		}
 
-		Info.Println(vals)
+		infoLog.Println(vals)
 	}
 }
 
+// SetActivation sets the activation used by every neuron in this layer. A nil
+// activation turns the layer back into binary neurons
+func (l *NetworkLayer) SetActivation(activation Activation) {
+	l.Activation = activation
+	l.EachNeuron(func(n *Neuron) {
+		n.Activation = activation
+	})
+}
+
+// random returns the layer's random source, falling back to the global one
+func (l *NetworkLayer) random() *rand.Rand {
+	if l.rng == nil {
+		return globalRand
+	}
+
+	return l.rng
+}
+
 // Width returns the width of this current layer
 func (l *NetworkLayer) Width() int {
 	return len(l.Neurons)
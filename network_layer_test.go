@@ -1,4 +1,4 @@
-package main
+package ann
 
 import (
 	"math/rand"
@@ -0,0 +1,414 @@
+package ann
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// NetworkSpec is a declarative description of a network and how to train it,
+// so experiments can be versioned as files
+type NetworkSpec struct {
+	Layers []*LayerSpec `json:"layers"`
+
+	// Seed seeds the random source used to build and train the network. Leaving
+	// it at zero picks a random seed
+	Seed     int64         `json:"seed"`
+	Training *TrainingSpec `json:"training"`
+}
+
+// LayerSpec describes a single layer of a network spec
+type LayerSpec struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Activation is the registered name of the layer's activation. Parameters
+	// of configurable activations go in ActivationParams, e.g. {"alpha": 0.1}
+	Activation       string          `json:"activation"`
+	ActivationParams json.RawMessage `json:"activation_params"`
+
+	InhibitoryDensity float64           `json:"inhibitory_density"`
+	Connectivity      *ConnectivitySpec `json:"connectivity"`
+	Init              *InitSpec         `json:"init"`
+
+	// Dropout and DropConnect are the fractions of the layer's neurons and
+	// incoming connections to drop from each training run
+	Dropout     float64 `json:"dropout"`
+	DropConnect float64 `json:"drop_connect"`
+
+	// Regularization penalizes and constrains the layer's incoming weights,
+	// e.g. {"l2": 0.001, "max_norm": 3}
+	Regularization *Regularization `json:"regularization"`
+
+	// Softmax normalizes the layer into probabilities, e.g. {"per_row": true}
+	Softmax *Softmax `json:"softmax"`
+}
+
+// ConnectivitySpec describes how a layer is wired to the previous one
+type ConnectivitySpec struct {
+	Pattern string `json:"pattern"`
+	Radius  int    `json:"radius"`
+}
+
+// InitSpec describes how the incoming weights of a layer are initialized.
+// Scheme is the registered name of an initializer and the other fields are the
+// parameters of the schemes that use them: Min and Max for "uniform", Mean and
+// StdDev for "normal", Uniform for "xavier", "he" and "lecun", Gain for
+// "orthogonal" and Value for "constant". Parameters left out keep the scheme's
+// defaults, while explicit zeros are used as given
+type InitSpec struct {
+	Scheme  string   `json:"scheme"`
+	Min     *float64 `json:"min"`
+	Max     *float64 `json:"max"`
+	Mean    *float64 `json:"mean"`
+	StdDev  *float64 `json:"std_dev"`
+	Uniform bool     `json:"uniform"`
+	Gain    *float64 `json:"gain"`
+	Value   *float64 `json:"value"`
+}
+
+// TrainingSpec holds the training hyperparameters of a network spec
+type TrainingSpec struct {
+	// BatchSize is the number of inputs per update, with -1 meaning the full
+	// set of inputs
+	BatchSize  int    `json:"batch_size"`
+	Debug      bool   `json:"debug"`
+	Engine     string `json:"engine"`
+	Iterations int    `json:"iterations"`
+
+	// LearningRate is the learning rate of the engine, or of its optimizer.
+	// Without one the gradient engine uses DefaultGradientLearningRate and the
+	// default engine DefaultEvaluatorLearningRate
+	LearningRate float64 `json:"learning_rate"`
+
+	// Loss is the registered name of the loss to train against, defaulting to
+	// the one that pairs with the output layer. Parameters go in LossParams,
+	// e.g. {"delta": 0.5}
+	Loss       string          `json:"loss"`
+	LossParams json.RawMessage `json:"loss_params"`
+
+	// GradientClipping limits the gradients of every update of the gradient
+	// engine, e.g. {"norm": 1}
+	GradientClipping *GradientClipping `json:"gradient_clipping"`
+
+	// Optimizer is the registered name of the gradient engine's optimizer,
+	// defaulting to "sgd". Hyperparameters other than the learning rate go in
+	// OptimizerParams, e.g. {"momentum": 0.8}
+	Optimizer       string          `json:"optimizer"`
+	OptimizerParams json.RawMessage `json:"optimizer_params"`
+
+	// Rollback undoes the last iteration if training diverges, see
+	// TrainingConfiguration.Rollback
+	Rollback bool `json:"rollback"`
+
+	// Sampling is either "weighted", the default, or "epoch"
+	Sampling string `json:"sampling"`
+
+	// Schedule is the registered name of the learning rate schedule, with its
+	// parameters in ScheduleParams, e.g. {"factor": 0.5, "step_size": 100}.
+	// Warmup ramps the learning rate up over that many iterations before the
+	// schedule takes over
+	Schedule       string          `json:"schedule"`
+	ScheduleParams json.RawMessage `json:"schedule_params"`
+	Warmup         int             `json:"warmup"`
+
+	// ValidationSplit is the fraction of the inputs to hold out for validation,
+	// evaluated every ValidationInterval iterations
+	ValidationSplit    float64        `json:"validation_split"`
+	ValidationInterval int            `json:"validation_interval"`
+	EarlyStopping      *EarlyStopping `json:"early_stopping"`
+}
+
+// LoadNetworkSpec reads a JSON network spec from the reader
+func LoadNetworkSpec(r io.Reader) (*NetworkSpec, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	spec := &NetworkSpec{}
+	if err := decoder.Decode(spec); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// LayerConfigurations converts the layers of the spec into the configurations
+// used to add them to a network
+func (s *NetworkSpec) LayerConfigurations() ([]LayerConfiguration, error) {
+	if len(s.Layers) == 0 {
+		return nil, fmt.Errorf("Network spec has no layers")
+	}
+
+	configs := make([]LayerConfiguration, len(s.Layers))
+	for i, layer := range s.Layers {
+		if layer.Width < 1 || layer.Height < 1 {
+			return nil, fmt.Errorf("Layer %d: invalid shape %dx%d", i, layer.Width, layer.Height)
+		}
+
+		config := LayerConfiguration{
+			Width:             layer.Width,
+			Height:            layer.Height,
+			Dropout:           layer.Dropout,
+			DropConnect:       layer.DropConnect,
+			InhibitoryDensity: layer.InhibitoryDensity,
+			Regularization:    layer.Regularization,
+			Softmax:           layer.Softmax,
+		}
+
+		if layer.Activation != "" {
+			activation, err := ActivationByName(layer.Activation)
+			if err != nil {
+				return nil, fmt.Errorf("Layer %d: %v %q", i, err, layer.Activation)
+			}
+
+			if len(layer.ActivationParams) > 0 {
+				if err := json.Unmarshal(layer.ActivationParams, activation); err != nil {
+					return nil, fmt.Errorf("Layer %d: invalid activation params: %v", i, err)
+				}
+			}
+			config.Activation = activation
+		}
+
+		if layer.Dropout < 0 || layer.Dropout >= 1 || layer.DropConnect < 0 || layer.DropConnect >= 1 {
+			return nil, fmt.Errorf("Layer %d: dropout rates must be at least 0 and less than 1", i)
+		}
+
+		if r := layer.Regularization; r != nil && (r.L1 < 0 || r.L2 < 0 || r.MaxNorm < 0) {
+			return nil, fmt.Errorf("Layer %d: regularization can't be negative", i)
+		}
+
+		if layer.Connectivity != nil {
+			config.Connectivity = layer.Connectivity.Pattern
+			config.Radius = layer.Connectivity.Radius
+		}
+
+		if layer.Init != nil {
+			initializer, err := layer.Init.NewInitializer()
+			if err != nil {
+				return nil, fmt.Errorf("Layer %d: %v", i, err)
+			}
+			config.Initializer = initializer
+		}
+
+		configs[i] = config
+	}
+
+	return configs, nil
+}
+
+// Build creates the network described by the spec along with a training
+// configuration for it. The returned configuration has no inputs yet
+func (s *NetworkSpec) Build() (*NeuralNetwork, *TrainingConfiguration, error) {
+	configs, err := s.LayerConfigurations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	network := NewNeuralNetwork(0, 0, 0)
+	if s.Seed != 0 {
+		network.SetSeed(s.Seed)
+	}
+	for i, config := range configs {
+		if _, err := network.AddConfiguredLayer(config); err != nil {
+			return nil, nil, fmt.Errorf("Layer %d: %v", i, err)
+		}
+	}
+
+	training := s.Training
+	if training == nil {
+		training = &TrainingSpec{}
+	}
+
+	engine, err := training.NewEngine()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loss, err := training.NewLoss()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sampling, err := SamplingByName(training.Sampling)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schedule, err := training.NewSchedule()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := &TrainingConfiguration{
+		BatchSize:          training.BatchSize,
+		Debug:              training.Debug,
+		EarlyStopping:      training.EarlyStopping,
+		Engine:             engine,
+		Loss:               loss,
+		Network:            network,
+		Rollback:           training.Rollback,
+		Sampling:           sampling,
+		Schedule:           schedule,
+		ValidationInterval: training.ValidationInterval,
+	}
+
+	if err := config.CheckEngine(); err != nil {
+		return nil, nil, err
+	}
+
+	return network, config, nil
+}
+
+// NewEngine creates the training engine named by the spec. The gradient
+// engine is used if no engine is named. The default engine has no gradients to
+// clip, so gradient clipping is an error for it
+func (t *TrainingSpec) NewEngine() (NetworkEngine, error) {
+	switch t.Engine {
+	case "", "gradient":
+		optimizer, err := t.NewOptimizer()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := t.CheckGradientClipping(); err != nil {
+			return nil, err
+		}
+
+		engine := NewGradientEngineWithOptimizer(optimizer)
+		engine.Clipping = t.GradientClipping
+		return engine, nil
+	case "default":
+		if c := t.GradientClipping; c != nil && *c != (GradientClipping{}) {
+			return nil, fmt.Errorf("The default engine doesn't support gradient clipping")
+		}
+
+		return &DefaultEvaluator{LearningRate: t.LearningRate}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown training engine %q", t.Engine)
+}
+
+// CheckGradientClipping makes sure the spec's gradient clipping limits aren't
+// negative
+func (t *TrainingSpec) CheckGradientClipping() error {
+	if c := t.GradientClipping; c != nil && (c.Norm < 0 || c.Value < 0) {
+		return fmt.Errorf("Gradient clipping limits can't be negative")
+	}
+
+	return nil
+}
+
+// NewLoss creates the loss named by the spec. Without a name it returns nil,
+// leaving the training configuration to use the DefaultLoss
+func (t *TrainingSpec) NewLoss() (Loss, error) {
+	if t.Loss == "" {
+		return nil, nil
+	}
+
+	loss, err := LossByName(t.Loss)
+	if err != nil {
+		return nil, fmt.Errorf("%v %q", err, t.Loss)
+	}
+
+	if len(t.LossParams) > 0 {
+		if err := json.Unmarshal(t.LossParams, loss); err != nil {
+			return nil, fmt.Errorf("Invalid loss params: %v", err)
+		}
+	}
+
+	return loss, nil
+}
+
+// NewSchedule creates the learning rate schedule named by the spec, wrapped in
+// a warmup if the spec has one. Without either it returns nil, keeping the
+// learning rate constant
+func (t *TrainingSpec) NewSchedule() (Schedule, error) {
+	if t.Warmup < 0 {
+		return nil, fmt.Errorf("Invalid warmup %d", t.Warmup)
+	}
+
+	var schedule Schedule
+	if t.Schedule != "" {
+		var err error
+		if schedule, err = ScheduleByName(t.Schedule); err != nil {
+			return nil, fmt.Errorf("%v %q", err, t.Schedule)
+		}
+
+		if len(t.ScheduleParams) > 0 {
+			if err := json.Unmarshal(t.ScheduleParams, schedule); err != nil {
+				return nil, fmt.Errorf("Invalid schedule params: %v", err)
+			}
+		}
+
+		if step, ok := schedule.(*StepDecaySchedule); ok && step.StepSize < 1 {
+			return nil, fmt.Errorf("Invalid step size %d", step.StepSize)
+		}
+	}
+
+	if t.Warmup > 0 {
+		schedule = NewWarmupSchedule(t.Warmup, schedule)
+	}
+
+	return schedule, nil
+}
+
+// NewOptimizer creates the optimizer named by the spec
+func (t *TrainingSpec) NewOptimizer() (Optimizer, error) {
+	name := t.Optimizer
+	if name == "" {
+		name = "sgd"
+	}
+
+	rate := t.LearningRate
+	if rate == 0 {
+		rate = DefaultGradientLearningRate
+	}
+
+	optimizer, err := OptimizerByName(name, rate)
+	if err != nil {
+		return nil, fmt.Errorf("%v %q", err, name)
+	}
+
+	if len(t.OptimizerParams) > 0 {
+		if err := json.Unmarshal(t.OptimizerParams, optimizer); err != nil {
+			return nil, fmt.Errorf("Invalid optimizer params: %v", err)
+		}
+	}
+
+	return optimizer, nil
+}
+
+// NewInitializer creates the initializer named by the spec with its parameters
+func (i *InitSpec) NewInitializer() (Initializer, error) {
+	initializer, err := InitializerByName(i.Scheme)
+	if err != nil {
+		return nil, fmt.Errorf("%v %q", err, i.Scheme)
+	}
+
+	set := func(dst *float64, val *float64) {
+		if val != nil {
+			*dst = *val
+		}
+	}
+
+	switch init := initializer.(type) {
+	case *ConstantInitializer:
+		set(&init.Value, i.Value)
+	case *HeInitializer:
+		init.Uniform = i.Uniform
+	case *LeCunInitializer:
+		init.Uniform = i.Uniform
+	case *NormalInitializer:
+		set(&init.Mean, i.Mean)
+		set(&init.StdDev, i.StdDev)
+	case *OrthogonalInitializer:
+		set(&init.Gain, i.Gain)
+	case *UniformInitializer:
+		set(&init.Min, i.Min)
+		set(&init.Max, i.Max)
+	case *XavierInitializer:
+		init.Uniform = i.Uniform
+	}
+
+	return initializer, nil
+}
@@ -0,0 +1,208 @@
+package ann
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestNetworkSpec(suite *testing.T) {
+	spec := `{
+		"layers": [
+			{"width": 4, "height": 4, "activation": "identity"},
+			{"width": 2, "height": 2, "activation": "leaky_relu", "activation_params": {"alpha": 0.2},
+			 "connectivity": {"pattern": "local", "radius": 0},
+			 "init": {"scheme": "uniform", "min": -0.5, "max": 0.5},
+			 "regularization": {"l1": 0.01, "max_norm": 2}, "dropout": 0.5},
+			{"width": 2, "height": 2, "activation": "tanh", "inhibitory_density": 1.0,
+			 "connectivity": {"pattern": "one_to_one"},
+			 "init": {"scheme": "constant", "value": 0.25}},
+			{"width": 1, "height": 1, "softmax": {"log": true},
+			 "init": {"scheme": "orthogonal", "gain": 0.5}}
+		],
+		"training": {"engine": "gradient", "learning_rate": 0.1, "iterations": 500,
+		             "optimizer": "momentum", "optimizer_params": {"momentum": 0.5},
+		             "loss": "huber", "loss_params": {"delta": 0.5},
+		             "schedule": "step", "schedule_params": {"factor": 0.1, "step_size": 50}, "warmup": 5,
+		             "gradient_clipping": {"norm": 2}, "rollback": true}
+	}`
+
+	this.Should("Build the network and training configuration described by a spec", suite,
+		func() {
+			loaded, err := LoadNetworkSpec(strings.NewReader(spec))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(loaded.Training.Iterations).To(Equal(500))
+
+			network, config, err := loaded.Build()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(network.GetDepth()).To(Equal(4))
+			Expect(config.Network).To(BeIdenticalTo(network))
+			Expect(config.Engine.(*GradientEngine).Optimizer).To(Equal(NewMomentum(0.1, 0.5)))
+			Expect(config.Loss).To(Equal(NewHuberLoss(0.5)))
+			Expect(config.Engine.(*GradientEngine).Clipping).To(Equal(&GradientClipping{Norm: 2}))
+			Expect(config.Rollback).To(BeTrue())
+			Expect(config.Schedule).To(Equal(NewWarmupSchedule(5, NewStepDecaySchedule(0.1, 50))))
+
+			layers := network.GetLayers()
+			Expect(layers[0].Activation).To(Equal(&Identity{}))
+			Expect(layers[1].Activation).To(Equal(NewLeakyReLU(0.2)))
+			Expect(layers[1].Regularization).To(Equal(&Regularization{L1: 0.01, MaxNorm: 2}))
+			Expect(layers[1].Dropout).To(Equal(0.5))
+			Expect(layers[3].Activation).To(BeNil())
+			Expect(layers[3].Softmax).To(Equal(&Softmax{Log: true}))
+
+			// A zero radius local window picks a single, scaled source neuron
+			layers[1].EachNeuron(func(n *Neuron) {
+				Expect(n.In).To(HaveLen(1))
+				Expect(n.In[0].Weight).To(BeNumerically(">=", -0.5))
+				Expect(n.In[0].Weight).To(BeNumerically("<", 0.5))
+			})
+
+			layers[2].EachNeuronWithIndex(func(n *Neuron, row, column int) {
+				Expect(n.Type).To(Equal(TypeInhibitory))
+				Expect(n.In).To(HaveLen(1))
+				Expect(n.In[0].Source).To(BeIdenticalTo(layers[1].Neurons[row][column]))
+				Expect(n.In[0].Weight).To(Equal(0.25))
+			})
+
+			Expect(layers[3].Neurons[0][0].In).To(HaveLen(4))
+
+			// A single row is orthonormal, so its squared length is the squared gain
+			length := 0.0
+			for _, conn := range layers[3].Neurons[0][0].In {
+				length += conn.Weight * conn.Weight
+			}
+			Expect(length).To(BeNumerically("~", 0.25, 1e-9))
+		})
+
+	this.Should("Build the default engine with the spec's learning rate", suite,
+		func() {
+			loaded, err := LoadNetworkSpec(strings.NewReader(`{"layers": [{"width": 2, "height": 2}, {"width": 1, "height": 1}],
+				"training": {"engine": "default", "learning_rate": 0.9}}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			_, config, err := loaded.Build()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Engine.(*DefaultEvaluator).GetLearningRate()).To(Equal(0.9))
+
+			loaded.Training.LearningRate = 0
+			_, config, err = loaded.Build()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Engine.(*DefaultEvaluator).GetLearningRate()).To(Equal(DefaultEvaluatorLearningRate))
+
+			loaded.Training.Engine = "gradient"
+			_, config, err = loaded.Build()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Engine.(*GradientEngine).Optimizer.GetLearningRate()).To(Equal(DefaultGradientLearningRate))
+		})
+
+	this.Should("Reject settings the default engine would ignore", suite,
+		func() {
+			for _, layer := range []string{
+				`"regularization": {"l2": 0.01}`,
+				`"dropout": 0.25`,
+				`"drop_connect": 0.1`,
+			} {
+				loaded, err := LoadNetworkSpec(strings.NewReader(`{"layers": [{"width": 2, "height": 2},
+					{"width": 1, "height": 1, ` + layer + `}], "training": {"engine": "default"}}`))
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, err = loaded.Build()
+				Expect(err).To(MatchError(ContainSubstring("the default engine doesn't support")), layer)
+			}
+
+			for _, batch := range []int{2, FullBatch} {
+				loaded, err := LoadNetworkSpec(strings.NewReader(fmt.Sprintf(`{"layers": [{"width": 2, "height": 2},
+					{"width": 1, "height": 1}], "training": {"engine": "default", "batch_size": %d,
+					"sampling": "epoch"}}`, batch)))
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, err = loaded.Build()
+				Expect(err).To(MatchError(ContainSubstring("doesn't support batches")), batch)
+			}
+
+			_, err := (&TrainingSpec{Engine: "default", GradientClipping: &GradientClipping{Norm: 1}}).NewEngine()
+			Expect(err).To(MatchError("The default engine doesn't support gradient clipping"))
+
+			network := NewNeuralNetworkWithSeed(0, 0, 0, 1)
+			network.AddConfiguredLayer(LayerConfiguration{Width: 2, Height: 2})
+			network.AddConfiguredLayer(LayerConfiguration{Width: 1, Height: 1,
+				Regularization: &Regularization{MaxNorm: 1}})
+			config := &TrainingConfiguration{Engine: &DefaultEvaluator{}, Network: network}
+			_, err = config.Engine.Train(1, config)
+			Expect(err).To(MatchError("Layer 1: the default engine doesn't support regularization"))
+
+			config.Engine = NewGradientEngine(0.1)
+			Expect(config.CheckEngine()).To(Succeed())
+		})
+
+	this.Should("Use explicit zeros in init parameters", suite,
+		func() {
+			loaded, err := LoadNetworkSpec(strings.NewReader(`{"layers": [
+				{"width": 3, "height": 3},
+				{"width": 4, "height": 4, "init": {"scheme": "uniform", "min": -1, "max": 0}},
+				{"width": 3, "height": 3, "init": {"scheme": "constant", "value": 0}},
+				{"width": 2, "height": 2, "init": {"scheme": "normal", "mean": 0.3, "std_dev": 0}}
+			]}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			network, _, err := loaded.Build()
+			Expect(err).ToNot(HaveOccurred())
+
+			layers := network.GetLayers()
+			eachIncoming(layers[1], func(conn *NeuronConnection) {
+				Expect(conn.Weight).To(BeNumerically(">=", -1))
+				Expect(conn.Weight).To(BeNumerically("<", 0))
+			})
+			eachIncoming(layers[2], func(conn *NeuronConnection) {
+				Expect(conn.Weight).To(Equal(0.0))
+			})
+			eachIncoming(layers[3], func(conn *NeuronConnection) {
+				Expect(conn.Weight).To(Equal(0.3))
+			})
+		})
+
+	this.Should("Reject unknown fields, activations and patterns", suite,
+		func() {
+			_, err := LoadNetworkSpec(strings.NewReader(`{"layerz": []}`))
+			Expect(err).To(HaveOccurred())
+
+			bad := []string{
+				`{"layers": [{"width": 1, "height": 1, "activation": "nope"}]}`,
+				`{"layers": [{"width": 0, "height": 1}]}`,
+				`{"layers": [{"width": 2, "height": 2}, {"width": 1, "height": 1, "connectivity": {"pattern": "one_to_one"}}]}`,
+				`{"layers": [{"width": 2, "height": 2}, {"width": 1, "height": 1, "connectivity": {"pattern": "ring"}}]}`,
+				`{"layers": [{"width": 2, "height": 2}, {"width": 1, "height": 1, "init": {"scheme": "magic"}}]}`,
+				`{"layers": [{"width": 1, "height": 1}], "training": {"engine": "quantum"}}`,
+				`{"layers": [{"width": 1, "height": 1}], "training": {"loss": "vibes"}}`,
+				`{"layers": [{"width": 1, "height": 1, "regularization": {"l2": -1}}]}`,
+				`{"layers": [{"width": 1, "height": 1, "dropout": 1}]}`,
+				`{"layers": [{"width": 1, "height": 1}], "training": {"schedule": "sawtooth"}}`,
+				`{"layers": [{"width": 1, "height": 1}], "training": {"warmup": -1}}`,
+				`{"layers": [{"width": 1, "height": 1}], "training": {"schedule": "step", "schedule_params": {"step_size": 0}}}`,
+				`{"layers": [{"width": 1, "height": 1}], "training": {"gradient_clipping": {"value": -1}}}`,
+			}
+
+			for _, data := range bad {
+				loaded, err := LoadNetworkSpec(strings.NewReader(data))
+				Expect(err).ToNot(HaveOccurred())
+
+				_, _, err = loaded.Build()
+				Expect(err).To(HaveOccurred(), data)
+			}
+		})
+
+	this.Should("Connect windows of neighboring neurons with local connectivity", suite,
+		func() {
+			layer1 := NewNetworkLayerWithDensity(5, 5, 0.0)
+			layer2 := NewNetworkLayerWithDensity(5, 5, 0.0)
+			layer1.ConnectLocal(layer2, 1)
+
+			Expect(layer2.Neurons[0][0].In).To(HaveLen(4))
+			Expect(layer2.Neurons[2][2].In).To(HaveLen(9))
+			Expect(layer2.Neurons[4][2].In).To(HaveLen(6))
+		})
+}
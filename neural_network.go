@@ -1,8 +1,9 @@
-package main
+package ann
 
 import (
 	"errors"
 	"io"
+	"math/rand"
 	"os"
 )
 
@@ -20,10 +21,28 @@ type NeuralNetwork struct {
 	PotentialStep      float64         `json:"potential_step"`
 	PotentialThreshold float64         `json:"potential_threshold"`
 	TimeStepSize       float64         `json:"time_step_size"`
+
+	// Workers is the number of goroutines each layer of a run is split across.
+	// Zero or one runs every layer sequentially. The goroutines are started by
+	// the first parallel run and reused by the ones after it
+	Workers int `json:"-"`
+	pool    *workerPool
+
+	// Seed seeds the random source used to build, initialize and train the
+	// network, so runs with the same seed are identical
+	Seed   int64 `json:"seed"`
+	rng    *rand.Rand
+	source *countingSource
 }
 
-// NewNeuralNetwork creates a new binary decision network
+// NewNeuralNetwork creates a new binary decision network with a random seed
 func NewNeuralNetwork(depth, width, height int) *NeuralNetwork {
+	return NewNeuralNetworkWithSeed(depth, width, height, globalRand.Int63())
+}
+
+// NewNeuralNetworkWithSeed creates a new binary decision network whose random
+// source is seeded with the given seed
+func NewNeuralNetworkWithSeed(depth, width, height int, seed int64) *NeuralNetwork {
 	layers := make([]*NetworkLayer, 0)
 
 	b := &NeuralNetwork{
@@ -34,6 +53,7 @@ func NewNeuralNetwork(depth, width, height int) *NeuralNetwork {
 		PotentialThreshold: 0.0, /* Always fire -- continuous neurons */
 		TimeStepSize:       1.0,
 	}
+	b.SetSeed(seed)
 
 	// Handle bad depth values properly -- just treat them like zero
 	for i := 0; i < depth; i++ {
@@ -47,17 +67,43 @@ func NewNeuralNetwork(depth, width, height int) *NeuralNetwork {
 // height. This will then connect the new layer with the previous layer of the
 // network if a previous layer exists
 func (n *NeuralNetwork) AddLayer(width, height int) {
+	n.AddConfiguredLayer(LayerConfiguration{
+		Width:             width,
+		Height:            height,
+		InhibitoryDensity: InhibitoryNeuronDensity,
+	})
+}
+
+// AddConfiguredLayer adds a new layer built from the given configuration and
+// connects it to the previous layer of the network if one exists. The new
+// layer is returned
+func (n *NeuralNetwork) AddConfiguredLayer(config LayerConfiguration) (*NetworkLayer, error) {
 	var currTail *NetworkLayer
 	if len(n.Layers) > 0 {
 		currTail = n.GetOutput()
 	}
-	newTail := NewNetworkLayer(width, height)
-	n.Layers = append(n.Layers, newTail)
+	newTail := NewNetworkLayerWithRand(config.Width, config.Height, config.InhibitoryDensity, n.GetRand())
+	newTail.SetActivation(config.Activation)
+	newTail.Dropout = config.Dropout
+	newTail.DropConnect = config.DropConnect
+	newTail.Regularization = config.Regularization
+	newTail.Softmax = config.Softmax
 
 	if currTail != nil {
 		// Wire 'em up
-		currTail.Connect(newTail)
+		if err := config.connect(currTail, newTail); err != nil {
+			return nil, err
+		}
+
+		if config.Initializer != nil {
+			if err := config.Initializer.Initialize(newTail); err != nil {
+				return nil, err
+			}
+		}
 	}
+	n.Layers = append(n.Layers, newTail)
+
+	return newTail, nil
 }
 
 // Clear resets the entire network back to 0 potential
@@ -67,13 +113,31 @@ func (n *NeuralNetwork) Clear() {
 	}
 }
 
-// Clone replicates the binary network
+// Clone replicates the binary network. The clone's random source picks up
+// where the network's is, and building the clone draws nothing from either
 func (n *NeuralNetwork) Clone() NetworkConfiguration {
-	clone := NewNeuralNetwork(0, 0, 0)
+	clone := NewNeuralNetworkWithSeed(0, 0, 0, n.Seed)
+	clone.CurrentTimeStep = n.CurrentTimeStep
+	clone.Debug = n.Debug
+	clone.PotentialStep = n.PotentialStep
+	clone.PotentialThreshold = n.PotentialThreshold
+	clone.TimeStepSize = n.TimeStepSize
+	clone.Workers = n.Workers
+	clone.setRandomDraws(n.randomDraws())
 	cloneMap := make(map[*Neuron]*Neuron)
 
 	n.EachLayer(func(layer *NetworkLayer) {
-		cloneLayer := NewNetworkLayer(layer.Width(), layer.Height())
+		// Every neuron is cloned below, so the layer starts out empty rather than
+		// drawing neuron types it would throw away
+		cloneLayer := &NetworkLayer{Neurons: make([][]*Neuron, layer.Width()), rng: clone.GetRand()}
+		for row := range cloneLayer.Neurons {
+			cloneLayer.Neurons[row] = make([]*Neuron, len(layer.Neurons[row]))
+		}
+		cloneLayer.Activation = layer.Activation
+		cloneLayer.Dropout = layer.Dropout
+		cloneLayer.DropConnect = layer.DropConnect
+		cloneLayer.Regularization = layer.Regularization
+		cloneLayer.Softmax = layer.Softmax
 		clone.Layers = append(clone.Layers, cloneLayer)
 
 		// Clone the current layer, and track the source neuron to clone neuron
@@ -130,6 +194,49 @@ func (n *NeuralNetwork) GetOutput() *NetworkLayer {
 	return n.Layers[len(n.Layers)-1]
 }
 
+// GetRand returns the network's random source. It isn't safe for concurrent
+// use
+func (n *NeuralNetwork) GetRand() *rand.Rand {
+	if n.rng == nil {
+		n.SetSeed(n.Seed)
+	}
+
+	return n.rng
+}
+
+// randomDraws returns the number of values drawn from the network's random
+// source since it was seeded
+func (n *NeuralNetwork) randomDraws() uint64 {
+	n.GetRand()
+	return n.source.draws
+}
+
+// setRandomDraws reseeds the network's random source and draws the given
+// number of values from it, putting it back where an earlier run left off
+func (n *NeuralNetwork) setRandomDraws(draws uint64) {
+	n.GetRand()
+	n.source.Seed(n.Seed)
+	n.source.skip(draws)
+}
+
+// GetSeed returns the seed of the network's random source
+func (n *NeuralNetwork) GetSeed() int64 {
+	return n.Seed
+}
+
+// ArgMax returns the position of the output neuron with the highest potential,
+// i.e. the winning class of a softmax output layer, or NoPosition if the
+// output layer has no neurons
+func (n *NeuralNetwork) ArgMax() Position {
+	return n.GetOutput().ArgMax()
+}
+
+// TopK returns the positions of the k output neurons with the highest
+// potentials, from highest to lowest
+func (n *NeuralNetwork) TopK(k int) []Position {
+	return n.GetOutput().TopK(k)
+}
+
 // Print prints out the current network's potential values
 func (n *NeuralNetwork) Print() {
 	// if n.Debug {
@@ -146,7 +253,8 @@ func (n *NeuralNetwork) Print() {
 }
 
 // Run processes the current neural net with the provided set of inputs. Since
-// this is a binary neural net, the final solution is simply a true or false
+// this is a binary neural net, the final solution is simply a true or false. A
+// NumericError is returned if any potential stops being a finite number
 func (n *NeuralNetwork) Run(inputs [][]float64) error {
 	inputLayer := n.GetInput()
 	if len(inputs) != len(inputLayer.Neurons) ||
@@ -161,9 +269,16 @@ func (n *NeuralNetwork) Run(inputs [][]float64) error {
 		n.Potential = inputs[row][column]
 	})
 
+	// Every other layer starts off from its bias before any signal arrives
+	for _, layer := range n.Layers[1:] {
+		layer.EachNeuron(func(neuron *Neuron) {
+			neuron.Potential = neuron.Bias
+		})
+	}
+
 	// Skip the output layer, since we don't want to try to fire that
 	if n.Debug {
-		Debug.Println("Starting run")
+		debugLog.Println("Starting run")
 	}
 	// n.Print("")
 	for i := 0; i < len(n.Layers)-1; i++ {
@@ -176,10 +291,25 @@ func (n *NeuralNetwork) Run(inputs [][]float64) error {
 			io.WriteString(os.Stdout, "\n")
 		}
 
-		// Try to fire every neuron in the layer
-		layer.EachNeuron(func(neuron *Neuron) {
-			neuron.Fire()
-		})
+		// Apply the layer's activation to its total input, then try to fire every
+		// neuron in the layer
+		layer.Activate()
+		if err := layer.checkPotentials(i); err != nil {
+			return err
+		}
+
+		if n.Workers > 1 {
+			n.pull(layer, n.Layers[i+1])
+		} else {
+			layer.EachNeuron(func(neuron *Neuron) {
+				neuron.Fire()
+			})
+		}
+	}
+
+	n.GetOutput().Activate()
+	if err := n.GetOutput().checkPotentials(len(n.Layers) - 1); err != nil {
+		return err
 	}
 
 	if n.Debug {
@@ -192,6 +322,24 @@ func (n *NeuralNetwork) Run(inputs [][]float64) error {
 	return nil
 }
 
+// SetSeed reseeds the network's random source
+func (n *NeuralNetwork) SetSeed(seed int64) {
+	n.Seed = seed
+	n.source = newCountingSource(seed)
+	n.rng = rand.New(n.source)
+}
+
+// SetWorkers sets the number of goroutines each layer of a run is split
+// across, where zero or one runs sequentially. Goroutines started for the
+// previous count are stopped
+func (n *NeuralNetwork) SetWorkers(workers int) {
+	if n.pool != nil && n.pool.size != workers {
+		n.pool.stop()
+		n.pool = nil
+	}
+	n.Workers = workers
+}
+
 // SetDebug sets the debug level
 func (n *NeuralNetwork) SetDebug(debug bool) {
 	n.Debug = debug
@@ -1,4 +1,4 @@
-package main
+package ann
 
 import (
 	"math"
@@ -126,4 +126,90 @@ func TestNeuralNetworkConfiguration(t *testing.T) {
 
 			Expect(allZero).To(BeFalse())
 		})
+
+	this.Should("Apply each layer's activation during a run", t,
+		func() {
+			network := NewNeuralNetwork(0, 0, 0)
+			network.AddConfiguredLayer(LayerConfiguration{
+				Width: 2, Height: 2, Activation: &Identity{}})
+			network.AddConfiguredLayer(LayerConfiguration{
+				Width: 1, Height: 1, Activation: &Logistic{}})
+
+			input := genRandInput(2, 2)
+			network.Run(input)
+
+			total := 0.0
+			network.GetInput().EachNeuronWithIndex(func(n *Neuron, row, col int) {
+				total += n.Out[0].CalculateIntensity() * input[row][col]
+			})
+
+			output := network.GetOutput().Neurons[0][0]
+			Expect(output.Potential).To(BeNumerically("~", sigmoid(total), 1e-12))
+		})
+
+	this.Should("Start each layer past the input from its bias", t,
+		func() {
+			network := NewNeuralNetwork(0, 0, 0)
+			network.AddConfiguredLayer(LayerConfiguration{
+				Width: 2, Height: 2, Activation: &Identity{}})
+			network.AddConfiguredLayer(LayerConfiguration{
+				Width: 1, Height: 1, Activation: &Identity{}})
+
+			output := network.GetOutput().Neurons[0][0]
+			output.Bias = 0.4
+			network.Run(genInput(0.0, 2, 2))
+
+			Expect(output.Potential).To(Equal(0.4))
+
+			total := 0.4
+			for _, conn := range output.In {
+				total += conn.CalculateIntensity()
+			}
+			Expect(output.TotalInput()).To(BeNumerically("~", total, 1e-12))
+		})
+
+	this.Should("Clone the bias of every neuron", t,
+		func() {
+			network := NewNeuralNetwork(3, 2, 2)
+			network.EachLayer(func(layer *NetworkLayer) {
+				layer.EachNeuron(func(n *Neuron) {
+					n.Bias = rand.Float64()
+				})
+			})
+
+			clone := network.Clone()
+			for i, layer := range network.GetLayers() {
+				layer.EachNeuronWithIndex(func(n *Neuron, row, col int) {
+					Expect(clone.GetLayers()[i].Neurons[row][col].Bias).To(Equal(n.Bias))
+				})
+			}
+		})
+
+	this.Should("Clone without drawing any randomness, picking up the random source where it was", t,
+		func() {
+			network := NewNeuralNetworkWithSeed(0, 0, 0, 9)
+			network.AddConfiguredLayer(LayerConfiguration{Width: 3, Height: 3, InhibitoryDensity: 0.5})
+			network.AddConfiguredLayer(LayerConfiguration{Width: 2, Height: 2, InhibitoryDensity: 0.5})
+			network.GetRand().Perm(10)
+			network.Debug = true
+			network.SetWorkers(3)
+
+			rand.Seed(4)
+			expected := rand.Int63()
+			rand.Seed(4)
+			clone := network.Clone().(*NeuralNetwork)
+			Expect(rand.Int63()).To(Equal(expected))
+
+			Expect(clone.Debug).To(BeTrue())
+			Expect(clone.Workers).To(Equal(3))
+			for i, layer := range network.Layers {
+				layer.EachNeuronWithIndex(func(n *Neuron, row, column int) {
+					Expect(clone.Layers[i].Neurons[row][column].Type).To(Equal(n.Type))
+				})
+			}
+
+			for i := 0; i < 5; i++ {
+				Expect(clone.GetRand().Int63()).To(Equal(network.GetRand().Int63()))
+			}
+		})
 }
@@ -1,4 +1,4 @@
-package main
+package ann
 
 import "time"
 
@@ -12,11 +12,16 @@ const (
 
 // Neuron the basic building block of the neural network structure
 type Neuron struct {
-	FiredAt   time.Time           `json:"fired_at"`
-	In        []*NeuronConnection `json:"incoming"`
-	Out       []*NeuronConnection `json:"outgoing"`
-	Potential float64             `json:"potential"`
-	Type      int                 `json:"type"`
+	// Activation is the nonlinearity applied to the neuron's total input. Neurons
+	// without one are binary: they pass their full connection weight along
+	// whenever their potential crosses the PotentialThreshold
+	Activation Activation          `json:"-"`
+	Bias       float64             `json:"bias"`
+	FiredAt    time.Time           `json:"fired_at"`
+	In         []*NeuronConnection `json:"incoming"`
+	Out        []*NeuronConnection `json:"outgoing"`
+	Potential  float64             `json:"potential"`
+	Type       int                 `json:"type"`
 }
 
 // NewNeuron returns a new base neuron with no connections
@@ -32,12 +37,22 @@ func NewNeuron(nType int) *Neuron {
 // any of the connections to or from the source neuron
 func (n *Neuron) Clone() *Neuron {
 	clone := NewNeuron(n.Type)
+	clone.Activation = n.Activation
+	clone.Bias = n.Bias
 	clone.FiredAt = n.FiredAt
 	clone.Potential = n.Potential
 
 	return clone
 }
 
+// Activate applies the neuron's activation to its current potential. Binary
+// neurons are left untouched
+func (n *Neuron) Activate() {
+	if n.Activation != nil {
+		n.Potential = n.Activation.Activate(n.Potential)
+	}
+}
+
 // Connect connects the given neuron to another neuron and then adds their
 // connections to each neuron's appropriate connection list
 func (n *Neuron) Connect(target *Neuron) *NeuronConnection {
@@ -76,15 +91,68 @@ func (n *Neuron) Fire() bool {
 
 	if fired {
 		n.FiredAt = time.Now()
-		n.Potential = 0
+
+		// Binary neurons discharge once they fire, while activated neurons hold on
+		// to their output so it can be inspected after a run
+		if n.Activation == nil {
+			n.Potential = 0
+		}
 	}
 
 	return fired
 }
 
-// TotalInput calculates the total connection input on this neuron
+// firing reports whether the neuron fires its outgoing connections. Activated
+// neurons always fire, scaling each connection by their output
+func (n *Neuron) firing() bool {
+	return n.Activation != nil || n.Potential >= PotentialThreshold
+}
+
+// pull sets the neuron's potential to its bias plus the intensity of every
+// incoming connection whose source fires, added up in the order the
+// connections were attached
+func (n *Neuron) pull() {
+	potential := n.Bias
+	for _, conn := range n.In {
+		if conn.Source.firing() {
+			potential += conn.CalculateIntensity() * conn.Source.Signal()
+		}
+	}
+	n.Potential = potential
+}
+
+// discharge leaves a neuron whose outgoing connections were pulled the same
+// way firing them would have
+func (n *Neuron) discharge(at time.Time) {
+	if len(n.Out) == 0 || !n.firing() {
+		return
+	}
+
+	n.FiredAt = at
+	if n.Activation == nil {
+		n.Potential = 0
+	}
+}
+
+// Signal returns the value this neuron sends along each outgoing connection.
+// Activated neurons send their potential, while binary neurons send either
+// nothing or their full connection weight depending on the PotentialThreshold
+func (n *Neuron) Signal() float64 {
+	if n.Activation != nil {
+		return n.Potential
+	}
+
+	if n.Potential >= PotentialThreshold {
+		return 1.0
+	}
+
+	return 0.0
+}
+
+// TotalInput calculates the total connection input on this neuron, including
+// its bias
 func (n *Neuron) TotalInput() float64 {
-	total := 0.0
+	total := n.Bias
 	for _, conn := range n.In {
 		total += conn.CalculateIntensity()
 	}
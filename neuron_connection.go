@@ -1,6 +1,4 @@
-package main
-
-import "math"
+package ann
 
 var (
 
@@ -41,13 +39,18 @@ func NewNeuronConnection(src, target *Neuron) *NeuronConnection {
 // CalculateIntensity calculates the final intensity of this neuron's fire
 // event
 func (n *NeuronConnection) CalculateIntensity() float64 {
-	// Make sure excitatory neurons add to the potential and inhibitory neurons
-	// subtract from it
+	return n.Multiplier() * n.Weight
+}
+
+// Multiplier returns the signed connection count that scales this connection's
+// weight. Excitatory neurons add to the potential and inhibitory neurons
+// subtract from it
+func (n *NeuronConnection) Multiplier() float64 {
 	if n.Source.Type == TypeExcitatory {
-		return float64(n.Connections) * n.Weight
+		return float64(n.Connections)
 	}
 
-	return -1.0 * float64(n.Connections) * n.Weight
+	return -1.0 * float64(n.Connections)
 }
 
 // Strengthen strengthens this connection by 1 step
@@ -68,15 +71,11 @@ func (n *NeuronConnection) Weaken() int {
 }
 
 // Fire fires the current dendrite connection from the source neuron to the
-// target neuron
+// target neuron. A potential that stops being finite is left for the network's
+// Run to report as a NumericError
 func (n *NeuronConnection) Fire() bool {
-	if n.Source.Potential >= PotentialThreshold {
-		n.Target.Potential += n.CalculateIntensity()
-
-		if math.IsNaN(n.Target.Potential) || math.IsInf(n.Target.Potential, 1) || math.IsInf(n.Target.Potential, -1) {
-			panic("fuck you")
-		}
-
+	if n.Source.firing() {
+		n.Target.Potential += n.CalculateIntensity() * n.Source.Signal()
 		return true
 	}
 
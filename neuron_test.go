@@ -1,4 +1,4 @@
-package main
+package ann
 
 import (
 	"testing"
@@ -9,7 +9,6 @@ import (
 )
 
 func init() {
-	initLoggers()
 	RegisterFailHandler(this.GomegaFailHandler)
 }
 
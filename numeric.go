@@ -0,0 +1,172 @@
+package ann
+
+import (
+	"fmt"
+	"math"
+)
+
+// NumericError is the error for when a value in the network stops being a
+// finite number, usually because training diverged. It points at the neuron
+// whose potential, bias or incoming weight went bad
+type NumericError struct {
+	Column int `json:"column"`
+	Layer  int `json:"layer"`
+
+	// Quantity is what went bad: "potential", "bias" or "weight"
+	Quantity string  `json:"quantity"`
+	Row      int     `json:"row"`
+	Value    float64 `json:"value"`
+}
+
+// Error describes the value and where it was found
+func (e *NumericError) Error() string {
+	return fmt.Sprintf("Non-finite %s %v at layer %d, row %d, column %d",
+		e.Quantity, e.Value, e.Layer, e.Row, e.Column)
+}
+
+// isFinite reports whether the value is neither NaN nor infinite
+func isFinite(val float64) bool {
+	return !math.IsNaN(val) && !math.IsInf(val, 0)
+}
+
+// checkPotentials returns a NumericError for the first neuron of the layer,
+// found at the given index of the network, whose potential isn't finite
+func (l *NetworkLayer) checkPotentials(index int) error {
+	for row, neurons := range l.Neurons {
+		for column, n := range neurons {
+			if !isFinite(n.Potential) {
+				return &NumericError{Column: column, Layer: index, Quantity: "potential", Row: row, Value: n.Potential}
+			}
+		}
+	}
+
+	return nil
+}
+
+// CheckWeights returns a NumericError for the first neuron of the network whose
+// bias or incoming weights aren't finite, or nil if they all are
+func CheckWeights(network NetworkConfiguration) error {
+	for i, layer := range network.GetLayers() {
+		for row, neurons := range layer.Neurons {
+			for column, n := range neurons {
+				if !isFinite(n.Bias) {
+					return &NumericError{Column: column, Layer: i, Quantity: "bias", Row: row, Value: n.Bias}
+				}
+
+				for _, conn := range n.In {
+					if !isFinite(conn.Weight) {
+						return &NumericError{Column: column, Layer: i, Quantity: "weight", Row: row, Value: conn.Weight}
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// GradientClipping limits the gradients of each update before the optimizer
+// takes its step. Value clips every gradient to [-Value, Value] and Norm
+// scales all of them down together when their L2 norm is larger than Norm.
+// Zero disables either
+type GradientClipping struct {
+	Norm  float64 `json:"norm"`
+	Value float64 `json:"value"`
+}
+
+// apply clips the gradients, by value first
+func (c *GradientClipping) apply(grads *Gradients) {
+	if c.Value > 0 {
+		grads.ClipValue(c.Value)
+	}
+
+	if c.Norm > 0 {
+		grads.ClipNorm(c.Norm)
+	}
+}
+
+// weightSnapshot is a copy of every bias and incoming weight of a network, in
+// layer, row, column order, used to roll back a training iteration that went
+// bad
+type weightSnapshot []float64
+
+// snapshotWeights copies the biases and weights of the network into the
+// snapshot, reusing its memory
+func snapshotWeights(network NetworkConfiguration, snapshot weightSnapshot) weightSnapshot {
+	snapshot = snapshot[:0]
+	for _, layer := range network.GetLayers() {
+		layer.EachNeuron(func(n *Neuron) {
+			snapshot = append(snapshot, n.Bias)
+			for _, conn := range n.In {
+				snapshot = append(snapshot, conn.Weight)
+			}
+		})
+	}
+
+	return snapshot
+}
+
+// restore copies the snapshot back into the network it was taken from
+func (s weightSnapshot) restore(network NetworkConfiguration) {
+	i := 0
+	for _, layer := range network.GetLayers() {
+		layer.EachNeuron(func(n *Neuron) {
+			n.Bias = s[i]
+			i++
+			for _, conn := range n.In {
+				conn.Weight = s[i]
+				i++
+			}
+		})
+	}
+}
+
+// rollback keeps a snapshot of the weights and optimizer state from the start
+// of each training iteration, so an iteration that produces non-finite values
+// can be undone when the training configuration asks for it
+type rollback struct {
+	config    *TrainingConfiguration
+	optimizer Optimizer
+	snapshot  weightSnapshot
+	state     *OptimizerState
+}
+
+// newRollback creates the rollback of a new training run with the given
+// optimizer, which may be nil for engines without one
+func (t *TrainingConfiguration) newRollback(optimizer Optimizer) *rollback {
+	return &rollback{config: t, optimizer: optimizer}
+}
+
+// save snapshots the current weights and optimizer state if rolling back is
+// enabled
+func (r *rollback) save() {
+	if !r.config.Rollback {
+		return
+	}
+
+	r.snapshot = snapshotWeights(r.config.Network, r.snapshot)
+	if r.optimizer != nil {
+		r.state = r.optimizer.GetState(r.config.Network)
+	}
+}
+
+// fail rolls the weights and optimizer state back to the last snapshot if
+// rolling back is enabled and the error is a NumericError, returning the error
+func (r *rollback) fail(err error) error {
+	if _, ok := err.(*NumericError); !ok || !r.config.Rollback || r.snapshot == nil {
+		return err
+	}
+
+	if r.config.Debug {
+		debugLog.Println("Rolling back to the last finite weights")
+	}
+	r.snapshot.restore(r.config.Network)
+
+	if r.state != nil {
+		if loadErr := r.optimizer.LoadState(r.config.Network, r.state); loadErr != nil {
+			errorLog.Println("Error while attempting to roll back the optimizer:", loadErr)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,157 @@
+package ann
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestNumeric(suite *testing.T) {
+	newNetwork := func() *NeuralNetwork {
+		return newTestNetwork(11,
+			LayerConfiguration{Width: 2, Height: 1, Activation: &Identity{}},
+			LayerConfiguration{Width: 2, Height: 2, Activation: &Identity{}},
+			LayerConfiguration{Width: 1, Height: 1, Activation: &Identity{}})
+	}
+
+	input := [][]float64{[]float64{1}, []float64{2}}
+
+	this.Should("Report where a potential stopped being finite instead of panicking", suite,
+		func() {
+			network := newNetwork()
+			network.Layers[1].Neurons[1][0].In[0].Weight = math.Inf(1)
+
+			expected := &NumericError{Column: 0, Layer: 1, Quantity: "potential", Row: 1, Value: math.Inf(1)}
+			Expect(network.Run(input)).To(Equal(expected))
+			Expect(NewGradientEngine(0.5).Run(input, network)).To(Equal(expected))
+			Expect(expected.Error()).To(Equal("Non-finite potential +Inf at layer 1, row 1, column 0"))
+
+			err := newNetwork().Run([][]float64{[]float64{1}, []float64{math.NaN()}})
+			Expect(err).To(BeAssignableToTypeOf(&NumericError{}))
+			Expect(err.(*NumericError).Layer).To(Equal(0))
+			Expect(err.(*NumericError).Row).To(Equal(1))
+		})
+
+	this.Should("Find weights and biases that aren't finite", suite,
+		func() {
+			network := newNetwork()
+			Expect(CheckWeights(network)).To(Succeed())
+
+			network.GetOutput().Neurons[0][0].In[2].Weight = math.NaN()
+			err := CheckWeights(network)
+			Expect(err).To(BeAssignableToTypeOf(&NumericError{}))
+			Expect(err.(*NumericError).Quantity).To(Equal("weight"))
+			Expect(err.(*NumericError).Layer).To(Equal(2))
+
+			network.Layers[1].Neurons[0][1].Bias = math.Inf(-1)
+			Expect(CheckWeights(network)).To(Equal(&NumericError{
+				Column: 1, Layer: 1, Quantity: "bias", Row: 0, Value: math.Inf(-1)}))
+		})
+
+	this.Should("Clip gradients by value and by norm", suite,
+		func() {
+			network := newNetwork()
+			conns := network.GetOutput().Neurons[0][0].In
+			bias := network.GetOutput().Neurons[0][0]
+
+			grads := NewGradients()
+			grads.Weights[conns[0]] = 3
+			grads.Weights[conns[1]] = -0.5
+			grads.Biases[bias] = -4
+			grads.ClipValue(1)
+			Expect(grads.Weights[conns[0]]).To(Equal(1.0))
+			Expect(grads.Weights[conns[1]]).To(Equal(-0.5))
+			Expect(grads.Biases[bias]).To(Equal(-1.0))
+
+			grads = NewGradients()
+			grads.Weights[conns[0]] = 3
+			grads.Biases[bias] = -4
+			grads.ClipNorm(1)
+			Expect(grads.Norm()).To(BeNumerically("~", 1, 1e-12))
+			Expect(grads.Weights[conns[0]]).To(BeNumerically("~", 0.6, 1e-12))
+
+			// Clipping happens once the gradients are averaged
+			engine := NewGradientEngine(1)
+			engine.Clipping = &GradientClipping{Value: 0.5}
+			weight := conns[1].Weight
+			grads = NewGradients()
+			grads.Weights[conns[1]] = 4
+			engine.ApplyGradients(grads, 2)
+			Expect(conns[1].Weight).To(BeNumerically("~", weight-0.5, 1e-12))
+		})
+
+	this.Should("Clip by norm the same way on every run with the same seed", suite,
+		func() {
+			train := func() []float64 {
+				network := NewNeuralNetworkWithSeed(0, 0, 0, 5)
+				network.AddConfiguredLayer(LayerConfiguration{Width: 3, Height: 3, Activation: &Identity{}})
+				network.AddConfiguredLayer(LayerConfiguration{Width: 8, Height: 8, Activation: &Tanh{},
+					Initializer: NewUniformInitializer(-1, 1)})
+				network.AddConfiguredLayer(LayerConfiguration{Width: 2, Height: 2, Activation: &Logistic{}})
+
+				engine := NewGradientEngine(0.5)
+				engine.Clipping = &GradientClipping{Norm: 0.01}
+				config := &TrainingConfiguration{
+					BatchSize: FullBatch,
+					Engine:    engine,
+					Inputs: []*InputConfiguration{&InputConfiguration{
+						Values:   [][]float64{{1, 0, 1}, {0, 1, 0}, {1, 1, 0}},
+						Expected: [][]float64{{1, 0}, {0, 1}},
+						Weight:   1,
+					}},
+					Network: network,
+				}
+				_, err := engine.Train(20, config)
+				Expect(err).ToNot(HaveOccurred())
+
+				weights := make([]float64, 0)
+				_, conns := parameterOrder(network)
+				for _, conn := range conns {
+					weights = append(weights, conn.Weight)
+				}
+
+				return weights
+			}
+
+			first := train()
+			for i := 0; i < 10; i++ {
+				Expect(train()).To(Equal(first))
+			}
+		})
+
+	this.Should("Stop training with an error and roll back when it diverges", suite,
+		func() {
+			for _, rollback := range []bool{false, true} {
+				engines := []NetworkEngine{
+					NewGradientEngine(1e300),
+					NewGradientEngineWithOptimizer(NewMomentum(1e300, 0.9)),
+					&DefaultEvaluator{LearningRate: 1e300},
+				}
+				for _, engine := range engines {
+					network := newNetwork()
+					config := &TrainingConfiguration{
+						Engine:   engine,
+						Inputs:   []*InputConfiguration{&InputConfiguration{Values: input, Expected: [][]float64{{1e10}}, Weight: 1}},
+						Network:  network,
+						Rollback: rollback,
+					}
+
+					history, err := engine.Train(100, config)
+					Expect(err).To(BeAssignableToTypeOf(&NumericError{}))
+					Expect(len(history.Records)).To(BeNumerically("<", 100))
+
+					if rollback {
+						Expect(CheckWeights(network)).To(Succeed())
+
+						// The optimizer is rolled back too, so its state can still be saved
+						if gradient, ok := engine.(*GradientEngine); ok {
+							Expect(SaveCheckpoint(&bytes.Buffer{}, network, gradient.Optimizer, nil)).To(Succeed())
+						}
+					}
+				}
+			}
+		})
+}
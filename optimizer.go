@@ -0,0 +1,402 @@
+package ann
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	// ErrUnknownOptimizer is the error for when an optimizer is requested by a
+	// name that isn't registered
+	ErrUnknownOptimizer = errors.New("Unknown optimizer")
+)
+
+// Optimizer applies gradients to the weights and biases of a network. Any
+// per-parameter state, such as velocities or moment estimates, is owned by the
+// optimizer and can be exported with GetState so training can be resumed
+type Optimizer interface {
+	GetLearningRate() float64
+	GetState(network NetworkConfiguration) *OptimizerState
+	LoadState(network NetworkConfiguration, state *OptimizerState) error
+	Name() string
+	SetLearningRate(rate float64)
+
+	// Update takes a single step using gradients that have already been
+	// averaged over their samples
+	Update(grads *Gradients)
+}
+
+// OptimizerByName returns a new optimizer registered under the given name with
+// its default hyperparameters and the given learning rate
+func OptimizerByName(name string, rate float64) (Optimizer, error) {
+	switch name {
+	case "adagrad":
+		return NewAdaGrad(rate), nil
+	case "adam":
+		return NewAdam(rate), nil
+	case "momentum":
+		return NewMomentum(rate, 0.9), nil
+	case "nesterov":
+		return NewNesterov(rate, 0.9), nil
+	case "rmsprop":
+		return NewRMSProp(rate), nil
+	case "sgd":
+		return NewSGD(rate), nil
+	}
+
+	return nil, ErrUnknownOptimizer
+}
+
+// OptimizerState is the exported per-parameter state of an optimizer. Slots
+// are addressed by the index of each connection and neuron in the network
+type OptimizerState struct {
+	Step  int                   `json:"step"`
+	Slots map[string]*SlotState `json:"slots"`
+}
+
+// SlotState holds a single named piece of optimizer state for every weight and
+// bias in a network. Weights are ordered by connection, walking the outgoing
+// connections of each neuron, and biases by neuron, both in layer, row,
+// column order
+type SlotState struct {
+	Biases  []float64 `json:"biases"`
+	Weights []float64 `json:"weights"`
+}
+
+// optimizerSlots tracks the named per-parameter state of an optimizer, keyed
+// by the connection or neuron owning each parameter
+type optimizerSlots struct {
+	step  int
+	slots map[string]map[interface{}]float64
+}
+
+// slot returns the named slot, creating it if needed
+func (o *optimizerSlots) slot(name string) map[interface{}]float64 {
+	if o.slots == nil {
+		o.slots = make(map[string]map[interface{}]float64)
+	}
+
+	if o.slots[name] == nil {
+		o.slots[name] = make(map[interface{}]float64)
+	}
+
+	return o.slots[name]
+}
+
+// GetState exports the optimizer state for the given network
+func (o *optimizerSlots) GetState(network NetworkConfiguration) *OptimizerState {
+	neurons, conns := parameterOrder(network)
+	state := &OptimizerState{Step: o.step, Slots: make(map[string]*SlotState)}
+
+	for name, values := range o.slots {
+		slot := &SlotState{
+			Biases:  make([]float64, len(neurons)),
+			Weights: make([]float64, len(conns)),
+		}
+
+		for i, n := range neurons {
+			slot.Biases[i] = values[n]
+		}
+
+		for i, conn := range conns {
+			slot.Weights[i] = values[conn]
+		}
+
+		state.Slots[name] = slot
+	}
+
+	return state
+}
+
+// LoadState replaces the optimizer state with the state exported for a network
+// of the same shape
+func (o *optimizerSlots) LoadState(network NetworkConfiguration, state *OptimizerState) error {
+	neurons, conns := parameterOrder(network)
+	slots := make(map[string]map[interface{}]float64)
+
+	for name, slot := range state.Slots {
+		if len(slot.Biases) != len(neurons) || len(slot.Weights) != len(conns) {
+			return fmt.Errorf("Optimizer state %q does not match the network", name)
+		}
+
+		values := make(map[interface{}]float64)
+		for i, n := range neurons {
+			values[n] = slot.Biases[i]
+		}
+
+		for i, conn := range conns {
+			values[conn] = slot.Weights[i]
+		}
+
+		slots[name] = values
+	}
+
+	o.step = state.Step
+	o.slots = slots
+
+	return nil
+}
+
+// eachParameter performs some function against every weight and bias with a
+// gradient, passing the key used for its optimizer state
+func eachParameter(grads *Gradients, do func(key interface{}, param *float64, grad float64)) {
+	for conn, grad := range grads.Weights {
+		do(conn, &conn.Weight, grad)
+	}
+
+	for n, grad := range grads.Biases {
+		do(n, &n.Bias, grad)
+	}
+}
+
+// parameterOrder returns every neuron and connection of the network in the
+// order used to address optimizer state
+func parameterOrder(network NetworkConfiguration) ([]*Neuron, []*NeuronConnection) {
+	neurons := make([]*Neuron, 0)
+	conns := make([]*NeuronConnection, 0)
+
+	network.EachLayer(func(layer *NetworkLayer) {
+		layer.EachNeuron(func(n *Neuron) {
+			neurons = append(neurons, n)
+			conns = append(conns, n.Out...)
+		})
+	})
+
+	return neurons, conns
+}
+
+// SGD is plain stochastic gradient descent
+type SGD struct {
+	optimizerSlots
+	LearningRate float64 `json:"learning_rate"`
+}
+
+// NewSGD creates a new stochastic gradient descent optimizer
+func NewSGD(rate float64) *SGD {
+	return &SGD{LearningRate: rate}
+}
+
+// GetLearningRate returns the learning rate
+func (o *SGD) GetLearningRate() float64 {
+	return o.LearningRate
+}
+
+// Name returns the registered name of the optimizer
+func (o *SGD) Name() string {
+	return "sgd"
+}
+
+// SetLearningRate sets the learning rate
+func (o *SGD) SetLearningRate(rate float64) {
+	o.LearningRate = rate
+}
+
+// Update steps each parameter against its gradient
+func (o *SGD) Update(grads *Gradients) {
+	o.step++
+	eachParameter(grads, func(key interface{}, param *float64, grad float64) {
+		*param -= o.LearningRate * grad
+	})
+}
+
+// Momentum is gradient descent that accumulates a velocity across steps
+type Momentum struct {
+	optimizerSlots
+	LearningRate float64 `json:"learning_rate"`
+	Momentum     float64 `json:"momentum"`
+}
+
+// NewMomentum creates a new momentum optimizer
+func NewMomentum(rate, momentum float64) *Momentum {
+	return &Momentum{LearningRate: rate, Momentum: momentum}
+}
+
+// GetLearningRate returns the learning rate
+func (o *Momentum) GetLearningRate() float64 {
+	return o.LearningRate
+}
+
+// Name returns the registered name of the optimizer
+func (o *Momentum) Name() string {
+	return "momentum"
+}
+
+// SetLearningRate sets the learning rate
+func (o *Momentum) SetLearningRate(rate float64) {
+	o.LearningRate = rate
+}
+
+// Update steps each parameter along its velocity
+func (o *Momentum) Update(grads *Gradients) {
+	o.step++
+	velocity := o.slot("velocity")
+	eachParameter(grads, func(key interface{}, param *float64, grad float64) {
+		velocity[key] = o.Momentum*velocity[key] - o.LearningRate*grad
+		*param += velocity[key]
+	})
+}
+
+// Nesterov is momentum gradient descent that evaluates the gradient at the
+// look ahead position
+type Nesterov struct {
+	optimizerSlots
+	LearningRate float64 `json:"learning_rate"`
+	Momentum     float64 `json:"momentum"`
+}
+
+// NewNesterov creates a new Nesterov accelerated gradient optimizer
+func NewNesterov(rate, momentum float64) *Nesterov {
+	return &Nesterov{LearningRate: rate, Momentum: momentum}
+}
+
+// GetLearningRate returns the learning rate
+func (o *Nesterov) GetLearningRate() float64 {
+	return o.LearningRate
+}
+
+// Name returns the registered name of the optimizer
+func (o *Nesterov) Name() string {
+	return "nesterov"
+}
+
+// SetLearningRate sets the learning rate
+func (o *Nesterov) SetLearningRate(rate float64) {
+	o.LearningRate = rate
+}
+
+// Update steps each parameter using the look ahead formulation of Nesterov's
+// method, so the stored parameters stay at the look ahead position
+func (o *Nesterov) Update(grads *Gradients) {
+	o.step++
+	velocity := o.slot("velocity")
+	eachParameter(grads, func(key interface{}, param *float64, grad float64) {
+		prev := velocity[key]
+		velocity[key] = o.Momentum*prev - o.LearningRate*grad
+		*param += -o.Momentum*prev + (1+o.Momentum)*velocity[key]
+	})
+}
+
+// AdaGrad scales the learning rate of each parameter down by its accumulated
+// squared gradients
+type AdaGrad struct {
+	optimizerSlots
+	Epsilon      float64 `json:"epsilon"`
+	LearningRate float64 `json:"learning_rate"`
+}
+
+// NewAdaGrad creates a new AdaGrad optimizer
+func NewAdaGrad(rate float64) *AdaGrad {
+	return &AdaGrad{Epsilon: 1e-8, LearningRate: rate}
+}
+
+// GetLearningRate returns the learning rate
+func (o *AdaGrad) GetLearningRate() float64 {
+	return o.LearningRate
+}
+
+// Name returns the registered name of the optimizer
+func (o *AdaGrad) Name() string {
+	return "adagrad"
+}
+
+// SetLearningRate sets the learning rate
+func (o *AdaGrad) SetLearningRate(rate float64) {
+	o.LearningRate = rate
+}
+
+// Update steps each parameter by its gradient scaled by its history
+func (o *AdaGrad) Update(grads *Gradients) {
+	o.step++
+	history := o.slot("squared_gradients")
+	eachParameter(grads, func(key interface{}, param *float64, grad float64) {
+		history[key] += grad * grad
+		*param -= o.LearningRate * grad / (math.Sqrt(history[key]) + o.Epsilon)
+	})
+}
+
+// RMSProp scales the learning rate of each parameter down by a moving average
+// of its squared gradients
+type RMSProp struct {
+	optimizerSlots
+	Decay        float64 `json:"decay"`
+	Epsilon      float64 `json:"epsilon"`
+	LearningRate float64 `json:"learning_rate"`
+}
+
+// NewRMSProp creates a new RMSProp optimizer
+func NewRMSProp(rate float64) *RMSProp {
+	return &RMSProp{Decay: 0.9, Epsilon: 1e-8, LearningRate: rate}
+}
+
+// GetLearningRate returns the learning rate
+func (o *RMSProp) GetLearningRate() float64 {
+	return o.LearningRate
+}
+
+// Name returns the registered name of the optimizer
+func (o *RMSProp) Name() string {
+	return "rmsprop"
+}
+
+// SetLearningRate sets the learning rate
+func (o *RMSProp) SetLearningRate(rate float64) {
+	o.LearningRate = rate
+}
+
+// Update steps each parameter by its gradient scaled by its recent history
+func (o *RMSProp) Update(grads *Gradients) {
+	o.step++
+	average := o.slot("mean_squared_gradients")
+	eachParameter(grads, func(key interface{}, param *float64, grad float64) {
+		average[key] = o.Decay*average[key] + (1-o.Decay)*grad*grad
+		*param -= o.LearningRate * grad / (math.Sqrt(average[key]) + o.Epsilon)
+	})
+}
+
+// Adam keeps bias corrected moving averages of both the gradient and the
+// squared gradient of each parameter
+type Adam struct {
+	optimizerSlots
+	Beta1        float64 `json:"beta1"`
+	Beta2        float64 `json:"beta2"`
+	Epsilon      float64 `json:"epsilon"`
+	LearningRate float64 `json:"learning_rate"`
+}
+
+// NewAdam creates a new Adam optimizer with the usual defaults for its decay
+// rates
+func NewAdam(rate float64) *Adam {
+	return &Adam{Beta1: 0.9, Beta2: 0.999, Epsilon: 1e-8, LearningRate: rate}
+}
+
+// GetLearningRate returns the learning rate
+func (o *Adam) GetLearningRate() float64 {
+	return o.LearningRate
+}
+
+// Name returns the registered name of the optimizer
+func (o *Adam) Name() string {
+	return "adam"
+}
+
+// SetLearningRate sets the learning rate
+func (o *Adam) SetLearningRate(rate float64) {
+	o.LearningRate = rate
+}
+
+// Update steps each parameter using its bias corrected moment estimates
+func (o *Adam) Update(grads *Gradients) {
+	o.step++
+	first := o.slot("first_moment")
+	second := o.slot("second_moment")
+	correction1 := 1 - math.Pow(o.Beta1, float64(o.step))
+	correction2 := 1 - math.Pow(o.Beta2, float64(o.step))
+
+	eachParameter(grads, func(key interface{}, param *float64, grad float64) {
+		first[key] = o.Beta1*first[key] + (1-o.Beta1)*grad
+		second[key] = o.Beta2*second[key] + (1-o.Beta2)*grad*grad
+		*param -= o.LearningRate * (first[key] / correction1) /
+			(math.Sqrt(second[key]/correction2) + o.Epsilon)
+	})
+}
@@ -0,0 +1,139 @@
+package ann
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestOptimizers(suite *testing.T) {
+	names := []string{"adagrad", "adam", "momentum", "nesterov", "rmsprop", "sgd"}
+
+	input := [][]float64{
+		[]float64{0.1, 0.9},
+		[]float64{0.5, 0.3},
+	}
+
+	newConfig := func(optimizer Optimizer) *TrainingConfiguration {
+		network := NewNeuralNetwork(0, 0, 0)
+		network.AddConfiguredLayer(LayerConfiguration{
+			Width: 2, Height: 2, Activation: &Identity{}})
+		network.AddConfiguredLayer(LayerConfiguration{
+			Width: 3, Height: 3, Activation: &Tanh{}, InhibitoryDensity: 0.3})
+		network.AddConfiguredLayer(LayerConfiguration{
+			Width: 1, Height: 2, Activation: &Logistic{}})
+
+		return &TrainingConfiguration{
+			Engine: NewGradientEngineWithOptimizer(optimizer),
+			Inputs: []*InputConfiguration{
+				&InputConfiguration{
+					Expected: [][]float64{[]float64{0.2, 0.7}},
+					Values:   input,
+					Weight:   1.0,
+				},
+			},
+			Network: network,
+		}
+	}
+
+	squaredError := func(config *TrainingConfiguration) float64 {
+		config.Network.Run(input)
+		total := 0.0
+		config.Network.GetOutput().EachNeuronWithIndex(func(n *Neuron, row, column int) {
+			total += Evaluator.MeanSquaredError(config.Inputs[0].Expected[row][column], n.Potential)
+		})
+
+		return total
+	}
+
+	this.Should("Look up each optimizer by name", suite,
+		func() {
+			for _, name := range names {
+				optimizer, err := OptimizerByName(name, 0.3)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(optimizer.Name()).To(Equal(name))
+				Expect(optimizer.GetLearningRate()).To(Equal(0.3))
+
+				optimizer.SetLearningRate(0.1)
+				Expect(optimizer.GetLearningRate()).To(Equal(0.1))
+			}
+
+			_, err := OptimizerByName("nope", 0.1)
+			Expect(err).To(Equal(ErrUnknownOptimizer))
+		})
+
+	this.Should("Apply the textbook update rules", suite,
+		func() {
+			n1 := NewNeuron(TypeExcitatory)
+			n2 := NewNeuron(TypeExcitatory)
+			conn := n1.Connect(n2)
+
+			step := func(optimizer Optimizer, grad float64) {
+				grads := NewGradients()
+				grads.Weights[conn] = grad
+				optimizer.Update(grads)
+			}
+
+			conn.Weight = 1.0
+			momentum := NewMomentum(0.1, 0.9)
+			step(momentum, 1.0)
+			step(momentum, 1.0)
+			Expect(conn.Weight).To(BeNumerically("~", 1.0-0.1-0.19, 1e-12))
+
+			conn.Weight = 1.0
+			adagrad := NewAdaGrad(0.1)
+			step(adagrad, 2.0)
+			Expect(conn.Weight).To(BeNumerically("~", 0.9, 1e-6))
+
+			// Adam's bias correction makes its first step the learning rate
+			conn.Weight = 1.0
+			adam := NewAdam(0.01)
+			step(adam, 5.0)
+			Expect(conn.Weight).To(BeNumerically("~", 0.99, 1e-6))
+
+			conn.Weight = 1.0
+			rmsprop := NewRMSProp(0.01)
+			step(rmsprop, 3.0)
+			Expect(conn.Weight).To(BeNumerically("~", 1.0-0.01*3.0/math.Sqrt(0.9), 1e-6))
+		})
+
+	this.Should("Reduce the error with every optimizer", suite,
+		func() {
+			for _, name := range names {
+				optimizer, _ := OptimizerByName(name, 0.05)
+				config := newConfig(optimizer)
+
+				before := squaredError(config)
+				config.Engine.Train(300, config)
+				Expect(squaredError(config)).To(BeNumerically("<", before), name)
+			}
+		})
+
+	this.Should("Resume training exactly from a checkpoint", suite,
+		func() {
+			for _, name := range names {
+				optimizer, _ := OptimizerByName(name, 0.05)
+				config := newConfig(optimizer)
+				resumed := &TrainingConfiguration{Inputs: config.Inputs}
+
+				config.Engine.Train(50, config)
+
+				buf := &bytes.Buffer{}
+				Expect(SaveCheckpoint(buf, config.Network.(*NeuralNetwork), optimizer, nil)).To(Succeed())
+				network, loaded, _, err := LoadCheckpoint(buf)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(loaded.Name()).To(Equal(name))
+
+				resumed.Network = network
+				resumed.Engine = NewGradientEngineWithOptimizer(loaded)
+
+				config.Engine.Train(50, config)
+				resumed.Engine.Train(50, resumed)
+
+				Expect(squaredError(resumed)).To(Equal(squaredError(config)), name)
+			}
+		})
+}
@@ -0,0 +1,92 @@
+package ann
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// workerPool runs the jobs handed to it on a fixed number of goroutines, so a
+// run doesn't start new ones for every layer
+type workerPool struct {
+	size int
+	jobs chan func()
+}
+
+// newWorkerPool starts a pool of the given number of goroutines. They only
+// hold on to the job channel, so once the pool itself is dropped its
+// finalizer can stop them
+func newWorkerPool(size int) *workerPool {
+	jobs := make(chan func())
+	for w := 0; w < size; w++ {
+		go func() {
+			for job := range jobs {
+				job()
+			}
+		}()
+	}
+
+	pool := &workerPool{size: size, jobs: jobs}
+	runtime.SetFinalizer(pool, (*workerPool).stop)
+	return pool
+}
+
+// stop ends the pool's goroutines once they're done with their current job
+func (p *workerPool) stop() {
+	runtime.SetFinalizer(p, nil)
+	close(p.jobs)
+}
+
+// workerPool returns the network's pool, starting one the first time and
+// whenever Workers has changed since
+func (n *NeuralNetwork) workerPool() *workerPool {
+	if n.pool != nil && n.pool.size != n.Workers {
+		n.pool.stop()
+		n.pool = nil
+	}
+	if n.pool == nil {
+		n.pool = newWorkerPool(n.Workers)
+	}
+
+	return n.pool
+}
+
+// pull works out the potentials of the target layer from the source layer by
+// splitting its neurons across the network's worker pool. Rather than having the
+// source neurons fire, each target neuron adds up its own incoming connections,
+// so no two workers ever write to the same neuron and the results don't depend
+// on how the work was split. For the layered networks built by this package,
+// where every connection joins neighboring layers, they also match the ones of
+// a sequential run
+func (n *NeuralNetwork) pull(source, target *NetworkLayer) {
+	pool := n.workerPool()
+
+	neurons := make([]*Neuron, 0, target.Width()*target.Height())
+	target.EachNeuron(func(neuron *Neuron) {
+		neurons = append(neurons, neuron)
+	})
+
+	workers := n.Workers
+	if workers > len(neurons) {
+		workers = len(neurons)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		part := neurons[w*len(neurons)/workers : (w+1)*len(neurons)/workers]
+		pool.jobs <- func() {
+			defer wg.Done()
+			for _, neuron := range part {
+				neuron.pull()
+			}
+		}
+	}
+	wg.Wait()
+
+	// Only once every target is done can the binary sources discharge
+	firedAt := time.Now()
+	source.EachNeuron(func(neuron *Neuron) {
+		neuron.discharge(firedAt)
+	})
+}
@@ -0,0 +1,127 @@
+package ann
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+// newParallelNetwork builds a network of the given layer sizes with random
+// weights and biases
+func newParallelNetwork(seed int64, activation Activation, density float64, sizes ...int) *NeuralNetwork {
+	layers := make([]LayerConfiguration, len(sizes))
+	for i, size := range sizes {
+		layers[i] = LayerConfiguration{Width: size, Height: size, Activation: activation}
+		if i > 0 {
+			layers[i].InhibitoryDensity = density
+			layers[i].Initializer = NewUniformInitializer(-1, 1)
+		}
+	}
+
+	network := newTestNetwork(seed, layers...)
+	randomizeBiases(network)
+
+	return network
+}
+
+func TestParallel(suite *testing.T) {
+	potentials := func(network *NeuralNetwork) [][][]float64 {
+		layers := make([][][]float64, 0, len(network.Layers))
+		network.EachLayer(func(layer *NetworkLayer) {
+			grid := make([][]float64, layer.Width())
+			layer.EachNeuronWithIndex(func(n *Neuron, row, column int) {
+				grid[row] = append(grid[row], n.Potential)
+			})
+			layers = append(layers, grid)
+		})
+
+		return layers
+	}
+
+	this.Should("Match a sequential run for any number of workers", suite,
+		func() {
+			for _, activation := range []Activation{&Tanh{}, nil} {
+				sequential := newParallelNetwork(13, activation, 0.3, 4, 6, 5, 3)
+				inputs := make([][][]float64, 10)
+				for i := range inputs {
+					inputs[i] = newTestInput(sequential, 1)
+				}
+
+				for _, workers := range []int{2, 3, 8, 100} {
+					parallel := sequential.Clone().(*NeuralNetwork)
+					parallel.SetWorkers(workers)
+
+					for _, input := range inputs {
+						Expect(sequential.Run(input)).To(Succeed())
+						Expect(parallel.Run(input)).To(Succeed())
+						Expect(potentials(parallel)).To(Equal(potentials(sequential)))
+					}
+				}
+			}
+		})
+
+	this.Should("Mark the neurons that fired", suite,
+		func() {
+			network := newParallelNetwork(2, nil, 0, 2, 2)
+			network.SetWorkers(4)
+			Expect(network.Run([][]float64{[]float64{1, -1}, []float64{-1, -1}})).To(Succeed())
+
+			input := network.GetInput().Neurons
+			Expect(input[0][0].FiredAt.IsZero()).To(BeFalse())
+			Expect(input[0][0].Potential).To(Equal(0.0))
+			Expect(input[0][1].FiredAt.IsZero()).To(BeTrue())
+			Expect(input[0][1].Potential).To(Equal(-1.0))
+		})
+
+	this.Should("Reuse the same workers from one run to the next", suite,
+		func() {
+			network := newParallelNetwork(5, &Tanh{}, 0, 4, 4, 4, 2)
+			input := newTestInput(network, 1)
+
+			network.SetWorkers(3)
+			Expect(network.Run(input)).To(Succeed())
+			pool := network.pool
+			Expect(pool.size).To(Equal(3))
+			for i := 0; i < 10; i++ {
+				Expect(network.Run(input)).To(Succeed())
+				Expect(network.pool).To(BeIdenticalTo(pool))
+			}
+
+			network.SetWorkers(2)
+			Expect(network.pool).To(BeNil())
+			Expect(network.Run(input)).To(Succeed())
+			Expect(network.pool.size).To(Equal(2))
+
+			network.Workers = 4
+			Expect(network.Run(input)).To(Succeed())
+			Expect(network.pool.size).To(Equal(4))
+		})
+}
+
+// BenchmarkRun compares sequential runs with parallel ones. Each worker walks
+// the incoming connections of its neurons, which are scattered in memory for
+// layers wired up source first, so the parallel runs only come out ahead with
+// enough cores to go around
+func BenchmarkRun(b *testing.B) {
+	for _, workers := range []int{1, 2, 4, 8} {
+		name := fmt.Sprintf("workers=%d", workers)
+		if workers == 1 {
+			name = "sequential"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			network := newParallelNetwork(1, &Tanh{}, 0, 32, 32, 32, 10)
+			network.SetWorkers(workers)
+			input := newTestInput(network, 1)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := network.Run(input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
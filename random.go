@@ -0,0 +1,66 @@
+package ann
+
+import "math/rand"
+
+var (
+	// globalRand draws from the global math/rand source. It's used by anything
+	// that isn't tied to a seeded network
+	globalRand = rand.New(globalSource{})
+)
+
+// globalSource is a rand.Source backed by the global math/rand functions,
+// which are safe for concurrent use
+type globalSource struct{}
+
+// Int63 returns the next value of the global source
+func (globalSource) Int63() int64 {
+	return rand.Int63()
+}
+
+// Seed seeds the global source
+func (globalSource) Seed(seed int64) {
+	rand.Seed(seed)
+}
+
+// newRand creates a new random source seeded with the given seed
+func newRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// countingSource is a seeded source that counts the values drawn from it, so a
+// checkpoint can put it back where it left off
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+// newCountingSource creates a new counting source seeded with the given seed
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed).(rand.Source64)}
+}
+
+// Int63 returns the next value of the source
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+// Uint64 returns the next value of the source
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+// Seed reseeds the source, starting the count over
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
+
+// skip draws values until the given number have been drawn since the source
+// was seeded
+func (s *countingSource) skip(draws uint64) {
+	for s.draws < draws {
+		s.Uint64()
+	}
+}
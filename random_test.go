@@ -0,0 +1,84 @@
+package ann
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestRandomSource(suite *testing.T) {
+	input := [][]float64{
+		[]float64{0.1, 0.9, 1.03},
+		[]float64{0.51, 0.5, 0.5},
+		[]float64{0.9, 0.85, 0.01},
+	}
+
+	newConfig := func(seed int64) *TrainingConfiguration {
+		network := NewNeuralNetworkWithSeed(0, 0, 0, seed)
+		network.AddConfiguredLayer(LayerConfiguration{Width: 3, Height: 3, Activation: &Identity{}})
+		network.AddConfiguredLayer(LayerConfiguration{
+			Width: 4, Height: 4, Activation: &Tanh{}, InhibitoryDensity: 0.4,
+			Initializer: &XavierInitializer{}})
+		network.AddConfiguredLayer(LayerConfiguration{Width: 2, Height: 2, Activation: &Logistic{}})
+
+		inputs := []*InputConfiguration{
+			&InputConfiguration{Values: input, Expected: [][]float64{[]float64{0, 1}, []float64{1, 0}}, Weight: 1},
+			&InputConfiguration{Values: input, Expected: [][]float64{[]float64{1, 0}, []float64{0, 1}}, Weight: 3},
+		}
+
+		return &TrainingConfiguration{
+			BatchSize: 1,
+			Engine:    NewGradientEngineWithOptimizer(NewAdam(0.01)),
+			Inputs:    inputs,
+			Network:   network,
+		}
+	}
+
+	save := func(network NetworkConfiguration) []byte {
+		buf := &bytes.Buffer{}
+		Expect(network.(*NeuralNetwork).SaveBinary(buf, PrecisionFloat64)).To(Succeed())
+		return buf.Bytes()
+	}
+
+	this.Should("Build and train identical networks from the same seed", suite,
+		func() {
+			first := newConfig(7)
+			second := newConfig(7)
+			Expect(save(second.Network)).To(Equal(save(first.Network)))
+
+			first.Engine.Train(20, first)
+			second.Engine.Train(20, second)
+			Expect(save(second.Network)).To(Equal(save(first.Network)))
+
+			other := newConfig(8)
+			Expect(save(other.Network)).ToNot(Equal(save(first.Network)))
+		})
+
+	this.Should("Record the seed in saved networks", suite,
+		func() {
+			network := newConfig(1234).Network.(*NeuralNetwork)
+
+			for _, format := range []string{"json", "binary"} {
+				buf := &bytes.Buffer{}
+				loaded := &NeuralNetwork{}
+				if format == "json" {
+					Expect(network.SaveJSON(buf)).To(Succeed())
+					Expect(loaded.LoadJSON(buf)).To(Succeed())
+				} else {
+					Expect(network.SaveBinary(buf, PrecisionFloat64)).To(Succeed())
+					Expect(loaded.LoadBinary(buf)).To(Succeed())
+				}
+
+				Expect(loaded.GetSeed()).To(Equal(int64(1234)))
+			}
+
+			Expect(network.Clone().GetSeed()).To(Equal(int64(1234)))
+		})
+
+	this.Should("Pick a new seed for every unseeded network", suite,
+		func() {
+			Expect(NewNeuralNetwork(0, 0, 0).GetSeed()).ToNot(Equal(NewNeuralNetwork(0, 0, 0).GetSeed()))
+		})
+}
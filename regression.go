@@ -0,0 +1,186 @@
+package ann
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+)
+
+// RegressionReport measures how well a network fits the continuous expected
+// values of a set of inputs. Residuals are the outputs minus the expected
+// values
+type RegressionReport struct {
+	RegressionMetrics
+
+	// Neurons are the metrics of each output neuron, indexed by row and column
+	Neurons [][]*NeuronRegressionMetrics `json:"neurons"`
+	Total   int                          `json:"total"`
+}
+
+// RegressionMetrics are the fit of a set of outputs against their expected
+// values
+type RegressionMetrics struct {
+	MAE      float64 `json:"mae"`
+	MaxError float64 `json:"max_error"`
+	MSE      float64 `json:"mse"`
+
+	// R2 is the coefficient of determination, 1 for a perfect fit and 0 for
+	// always predicting the mean. It's 0 when the expected values are constant
+	// but not perfectly fit. For a whole report it's the average R2 of the
+	// output neurons, each measured around the mean of its own expected values
+	R2   float64 `json:"r2"`
+	RMSE float64 `json:"rmse"`
+
+	// ResidualMean and ResidualStdDev show whether the outputs are biased and
+	// how much they scatter
+	ResidualMean   float64 `json:"residual_mean"`
+	ResidualStdDev float64 `json:"residual_std_dev"`
+}
+
+// NeuronRegressionMetrics are the regression metrics of a single output neuron
+type NeuronRegressionMetrics struct {
+	RegressionMetrics
+	Position
+}
+
+// regressionAccumulator collects expected values and residuals to calculate
+// regression metrics from
+type regressionAccumulator struct {
+	count       int
+	expected    float64
+	expectedSq  float64
+	residual    float64
+	residualSq  float64
+	absResidual float64
+	maxError    float64
+}
+
+// add adds an output and its expected value
+func (a *regressionAccumulator) add(expected, actual float64) {
+	residual := actual - expected
+	a.count++
+	a.expected += expected
+	a.expectedSq += expected * expected
+	a.residual += residual
+	a.residualSq += residual * residual
+	a.absResidual += math.Abs(residual)
+	a.maxError = math.Max(a.maxError, math.Abs(residual))
+}
+
+// metrics calculates the metrics of everything added so far
+func (a *regressionAccumulator) metrics() RegressionMetrics {
+	if a.count == 0 {
+		return RegressionMetrics{}
+	}
+
+	n := float64(a.count)
+	metrics := RegressionMetrics{
+		MAE:          a.absResidual / n,
+		MaxError:     a.maxError,
+		MSE:          a.residualSq / n,
+		ResidualMean: a.residual / n,
+	}
+	metrics.RMSE = math.Sqrt(metrics.MSE)
+	metrics.ResidualStdDev = math.Sqrt(math.Max(0, metrics.MSE-metrics.ResidualMean*metrics.ResidualMean))
+
+	// The total sum of squares is how far the expected values are from their
+	// own mean
+	totalSq := a.expectedSq - a.expected*a.expected/n
+	switch {
+	case totalSq > 1e-12*a.expectedSq:
+		metrics.R2 = 1 - a.residualSq/totalSq
+	case a.residualSq == 0:
+		metrics.R2 = 1
+	}
+
+	return metrics
+}
+
+// EvaluateRegression runs the network over every input and measures how well
+// its outputs fit the expected values, overall and for each output neuron
+func EvaluateRegression(network NetworkConfiguration, inputs []*InputConfiguration) (*RegressionReport, error) {
+	output := network.GetOutput()
+	total := &regressionAccumulator{}
+	neurons := make([][]*regressionAccumulator, output.Width())
+	for row := range neurons {
+		neurons[row] = make([]*regressionAccumulator, output.Height())
+		for column := range neurons[row] {
+			neurons[row][column] = &regressionAccumulator{}
+		}
+	}
+
+	for i, input := range inputs {
+		if err := network.Run(input.Values); err != nil {
+			return nil, fmt.Errorf("Input %d: %v", i, err)
+		}
+
+		if len(input.Expected) != output.Width() ||
+			len(input.Expected[0]) != output.Height() {
+			return nil, fmt.Errorf("Input %d: %v", i, ErrArraySizeMismatch)
+		}
+
+		output.EachNeuronWithIndex(func(n *Neuron, row, column int) {
+			total.add(input.Expected[row][column], n.Potential)
+			neurons[row][column].add(input.Expected[row][column], n.Potential)
+		})
+	}
+
+	report := &RegressionReport{
+		RegressionMetrics: total.metrics(),
+		Neurons:           make([][]*NeuronRegressionMetrics, len(neurons)),
+		Total:             len(inputs),
+	}
+
+	// Pooling every output into one R2 would measure them around the mean of
+	// all of them, which rewards outputs for merely having different means
+	r2, count := 0.0, 0
+	for row := range neurons {
+		report.Neurons[row] = make([]*NeuronRegressionMetrics, len(neurons[row]))
+		for column, acc := range neurons[row] {
+			report.Neurons[row][column] = &NeuronRegressionMetrics{
+				RegressionMetrics: acc.metrics(),
+				Position:          Position{Row: row, Column: column},
+			}
+			r2 += report.Neurons[row][column].R2
+			count++
+		}
+	}
+	if len(inputs) > 0 && count > 0 {
+		report.R2 = r2 / float64(count)
+	}
+
+	return report, nil
+}
+
+// WriteJSON writes the report as JSON
+func (r *RegressionReport) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// WriteText writes the report as a human readable table, listing the output
+// neurons from the worst fit to the best
+func (r *RegressionReport) WriteText(w io.Writer) error {
+	out := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(out, "inputs: %d\n", r.Total)
+	fmt.Fprintf(out, "mse: %.6f\nrmse: %.6f\nmae: %.6f\nr2: %.4f\nmax error: %.6f\n",
+		r.MSE, r.RMSE, r.MAE, r.R2, r.MaxError)
+
+	neurons := make([]*NeuronRegressionMetrics, 0)
+	for _, row := range r.Neurons {
+		neurons = append(neurons, row...)
+	}
+	sort.SliceStable(neurons, func(i, j int) bool {
+		return neurons[i].RMSE > neurons[j].RMSE
+	})
+
+	fmt.Fprintln(out, "\nrow\tcolumn\trmse\tmae\tmax error\tresidual mean\tresidual std dev\tr2")
+	for _, n := range neurons {
+		fmt.Fprintf(out, "%d\t%d\t%.6f\t%.6f\t%.6f\t%.6f\t%.6f\t%.4f\n",
+			n.Row, n.Column, n.RMSE, n.MAE, n.MaxError, n.ResidualMean, n.ResidualStdDev, n.R2)
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,123 @@
+package ann
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestRegression(suite *testing.T) {
+	// newPassthrough builds a network whose output is its input, so the inputs
+	// are the predictions being measured
+	newPassthrough := func(width, height int) *NeuralNetwork {
+		network := NewNeuralNetwork(0, 0, 0)
+		network.AddConfiguredLayer(LayerConfiguration{
+			Width: width, Height: height, Activation: &Identity{}})
+		network.AddConfiguredLayer(LayerConfiguration{
+			Width: width, Height: height, Activation: &Identity{},
+			Connectivity: ConnectOneToOne, Initializer: NewConstantInitializer(1)})
+
+		return network
+	}
+
+	newInput := func(values, expected []float64) *InputConfiguration {
+		return &InputConfiguration{
+			Values:   [][]float64{values},
+			Expected: [][]float64{expected},
+			Weight:   1,
+		}
+	}
+
+	this.Should("Measure the overall fit", suite,
+		func() {
+			inputs := []*InputConfiguration{
+				newInput([]float64{1.5}, []float64{1}),
+				newInput([]float64{2}, []float64{2}),
+				newInput([]float64{2}, []float64{3}),
+			}
+
+			report, err := EvaluateRegression(newPassthrough(1, 1), inputs)
+			Expect(err).ToNot(HaveOccurred())
+
+			// Residuals of 0.5, 0 and -1 against expected values with a total sum
+			// of squares of 2
+			Expect(report.Total).To(Equal(3))
+			Expect(report.MSE).To(BeNumerically("~", 1.25/3, 1e-12))
+			Expect(report.RMSE).To(BeNumerically("~", math.Sqrt(1.25/3), 1e-12))
+			Expect(report.MAE).To(BeNumerically("~", 0.5, 1e-12))
+			Expect(report.MaxError).To(Equal(1.0))
+			Expect(report.R2).To(BeNumerically("~", 1-1.25/2, 1e-12))
+			Expect(report.ResidualMean).To(BeNumerically("~", -0.5/3, 1e-12))
+			Expect(report.ResidualStdDev).To(BeNumerically("~",
+				math.Sqrt(1.25/3-0.25/9), 1e-12))
+		})
+
+	this.Should("Average the R2 of the output neurons rather than pooling them", suite,
+		func() {
+			// Each output is predicted by its own mean, an R2 of 0, but the outputs
+			// have very different means. Pooled, that would look like a good fit
+			inputs := []*InputConfiguration{
+				newInput([]float64{1, 101}, []float64{0, 100}),
+				newInput([]float64{1, 101}, []float64{2, 102}),
+			}
+
+			report, err := EvaluateRegression(newPassthrough(1, 2), inputs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Neurons[0][0].R2).To(BeNumerically("~", 0, 1e-12))
+			Expect(report.Neurons[0][1].R2).To(BeNumerically("~", 0, 1e-12))
+			Expect(report.R2).To(BeNumerically("~", 0, 1e-12))
+		})
+
+	this.Should("Measure each output neuron separately", suite,
+		func() {
+			inputs := []*InputConfiguration{
+				newInput([]float64{0, 1}, []float64{0, 2}),
+				newInput([]float64{1, 3}, []float64{1, 2}),
+			}
+
+			report, err := EvaluateRegression(newPassthrough(1, 2), inputs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Neurons).To(HaveLen(1))
+			Expect(report.Neurons[0]).To(HaveLen(2))
+
+			perfect := report.Neurons[0][0]
+			Expect(perfect.Position).To(Equal(Position{Row: 0, Column: 0}))
+			Expect(perfect.RMSE).To(Equal(0.0))
+			Expect(perfect.R2).To(Equal(1.0))
+
+			// A constant expected value that isn't fit has no R2 to speak of
+			poor := report.Neurons[0][1]
+			Expect(poor.Position).To(Equal(Position{Row: 0, Column: 1}))
+			Expect(poor.RMSE).To(Equal(1.0))
+			Expect(poor.ResidualMean).To(Equal(0.0))
+			Expect(poor.ResidualStdDev).To(Equal(1.0))
+			Expect(poor.R2).To(Equal(0.0))
+
+			out := &bytes.Buffer{}
+			Expect(report.WriteText(out)).To(Succeed())
+			lines := strings.Split(out.String(), "\n")
+			for i, line := range lines {
+				if strings.HasPrefix(line, "row") {
+					Expect(lines[i+1]).To(HavePrefix("0    1"))
+				}
+			}
+
+			out.Reset()
+			Expect(report.WriteJSON(out)).To(Succeed())
+			decoded := &RegressionReport{}
+			Expect(json.Unmarshal(out.Bytes(), decoded)).To(Succeed())
+			Expect(decoded).To(Equal(report))
+		})
+
+	this.Should("Reject expected values that don't fit the output layer", suite,
+		func() {
+			inputs := []*InputConfiguration{newInput([]float64{0}, []float64{0, 1})}
+			_, err := EvaluateRegression(newPassthrough(1, 1), inputs)
+			Expect(err).To(MatchError("Input 0: " + ErrArraySizeMismatch.Error()))
+		})
+}
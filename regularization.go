@@ -0,0 +1,86 @@
+package ann
+
+import "math"
+
+// Regularization keeps the weights coming in to a layer small. L1 and L2 add
+// L1 * |w| + L2 * w^2 for each incoming weight w to the loss the gradient
+// engine trains against, and MaxNorm rescales each neuron's incoming weights
+// after every update so their L2 norm is at most MaxNorm. Zero values disable
+// each of them
+type Regularization struct {
+	L1      float64 `json:"l1"`
+	L2      float64 `json:"l2"`
+	MaxNorm float64 `json:"max_norm"`
+}
+
+// Penalty returns the regularization term the layer's incoming weights add to
+// the loss
+func (r *Regularization) Penalty(layer *NetworkLayer) float64 {
+	penalty := 0.0
+	layer.EachNeuron(func(n *Neuron) {
+		for _, conn := range n.In {
+			penalty += r.L1*math.Abs(conn.Weight) + r.L2*conn.Weight*conn.Weight
+		}
+	})
+
+	return penalty
+}
+
+// addGradients adds the gradient of the penalty, scaled by the number of
+// samples in the gradients, to each of the layer's incoming weight gradients
+func (r *Regularization) addGradients(layer *NetworkLayer, grads *Gradients, samples int) {
+	if r.L1 == 0 && r.L2 == 0 {
+		return
+	}
+
+	scale := float64(samples)
+	layer.EachNeuron(func(n *Neuron) {
+		for _, conn := range n.In {
+			grad := 2 * r.L2 * conn.Weight
+			if conn.Weight > 0 {
+				grad += r.L1
+			} else if conn.Weight < 0 {
+				grad -= r.L1
+			}
+
+			grads.Weights[conn] += grad * scale
+		}
+	})
+}
+
+// constrain rescales the incoming weights of each of the layer's neurons whose
+// norm is larger than the MaxNorm
+func (r *Regularization) constrain(layer *NetworkLayer) {
+	if r.MaxNorm <= 0 {
+		return
+	}
+
+	layer.EachNeuron(func(n *Neuron) {
+		norm := 0.0
+		for _, conn := range n.In {
+			norm += conn.Weight * conn.Weight
+		}
+
+		norm = math.Sqrt(norm)
+		if norm <= r.MaxNorm {
+			return
+		}
+
+		for _, conn := range n.In {
+			conn.Weight *= r.MaxNorm / norm
+		}
+	})
+}
+
+// RegularizationPenalty returns the total regularization term that the layers
+// of the network add to the loss
+func RegularizationPenalty(network NetworkConfiguration) float64 {
+	penalty := 0.0
+	for _, layer := range network.GetLayers() {
+		if layer.Regularization != nil {
+			penalty += layer.Regularization.Penalty(layer)
+		}
+	}
+
+	return penalty
+}
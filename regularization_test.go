@@ -0,0 +1,110 @@
+package ann
+
+import (
+	"math"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestRegularization(suite *testing.T) {
+	newNetwork := func(regularization *Regularization) *NeuralNetwork {
+		return newTestNetwork(7,
+			LayerConfiguration{Width: 2, Height: 2, Activation: &Identity{}},
+			LayerConfiguration{Width: 3, Height: 3, Activation: &Tanh{}, Regularization: regularization,
+				Initializer: NewNormalInitializer(0, 2)},
+			LayerConfiguration{Width: 1, Height: 1, Activation: &Logistic{}, Regularization: regularization})
+	}
+
+	newConfig := func(network *NeuralNetwork) *TrainingConfiguration {
+		return &TrainingConfiguration{
+			Engine:  NewGradientEngine(0.5),
+			Network: network,
+			Inputs: []*InputConfiguration{
+				&InputConfiguration{Values: [][]float64{{0, 0}, {0, 1}}, Expected: [][]float64{{0.2}}, Weight: 1},
+				&InputConfiguration{Values: [][]float64{{1, 1}, {1, 0}}, Expected: [][]float64{{0.8}}, Weight: 1},
+			},
+		}
+	}
+
+	incomingNorm := func(n *Neuron) float64 {
+		norm := 0.0
+		for _, conn := range n.In {
+			norm += conn.Weight * conn.Weight
+		}
+
+		return math.Sqrt(norm)
+	}
+
+	this.Should("Penalize the weights coming in to a layer", suite,
+		func() {
+			network := newNetwork(&Regularization{L1: 0.1, L2: 0.5})
+			layer := network.GetOutput()
+			expected := 0.0
+			for _, conn := range layer.Neurons[0][0].In {
+				expected += 0.1*math.Abs(conn.Weight) + 0.5*conn.Weight*conn.Weight
+			}
+
+			Expect(layer.Regularization.Penalty(layer)).To(BeNumerically("~", expected, 1e-12))
+			Expect(RegularizationPenalty(network)).To(BeNumerically(">", expected))
+			Expect(RegularizationPenalty(newNetwork(nil))).To(Equal(0.0))
+		})
+
+	this.Should("Add the gradient of the penalty to the weight gradients", suite,
+		func() {
+			layer := newNetwork(nil).GetOutput()
+			conns := layer.Neurons[0][0].In
+			conns[0].Weight = 2
+			conns[1].Weight = -1
+			conns[2].Weight = 0
+
+			grads := NewGradients()
+			(&Regularization{L1: 0.1, L2: 0.5}).addGradients(layer, grads, 4)
+			Expect(grads.Weights[conns[0]]).To(BeNumerically("~", 4*(0.1+2), 1e-12))
+			Expect(grads.Weights[conns[1]]).To(BeNumerically("~", 4*(-0.1-1), 1e-12))
+			Expect(grads.Weights[conns[2]]).To(Equal(0.0))
+		})
+
+	this.Should("Shrink the weights with weight decay", suite,
+		func() {
+			plain := newNetwork(nil)
+			decayed := newNetwork(&Regularization{L2: 0.05})
+			NewGradientEngine(0.5).Train(300, newConfig(plain))
+			history, err := NewGradientEngine(0.5).Train(300, newConfig(decayed))
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 1; i < plain.GetDepth(); i++ {
+				plainNorm, decayedNorm := 0.0, 0.0
+				plain.Layers[i].EachNeuron(func(n *Neuron) { plainNorm += incomingNorm(n) })
+				decayed.Layers[i].EachNeuron(func(n *Neuron) { decayedNorm += incomingNorm(n) })
+				Expect(decayedNorm).To(BeNumerically("<", plainNorm))
+			}
+
+			// The reported loss includes the penalty
+			last := history.Records[len(history.Records)-1]
+			Expect(last.TrainingLoss).To(BeNumerically(">", RegularizationPenalty(decayed)))
+		})
+
+	this.Should("Keep each neuron's incoming weights within the max norm", suite,
+		func() {
+			network := newNetwork(&Regularization{MaxNorm: 0.5})
+			NewGradientEngine(0.5).Train(50, newConfig(network))
+
+			for _, layer := range network.GetLayers()[1:] {
+				layer.EachNeuron(func(n *Neuron) {
+					Expect(incomingNorm(n)).To(BeNumerically("<=", 0.5+1e-9))
+				})
+			}
+		})
+
+	this.Should("Refuse to train with the default evaluator, which can't regularize", suite,
+		func() {
+			for _, regularization := range []*Regularization{{L1: 0.01}, {L2: 0.01}, {MaxNorm: 1}} {
+				config := newConfig(newNetwork(regularization))
+				config.Engine = nil
+				_, err := Evaluator.Train(1, config)
+				Expect(err).To(MatchError("Layer 1: the default engine doesn't support regularization"))
+			}
+		})
+}
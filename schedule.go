@@ -0,0 +1,309 @@
+package ann
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+var (
+	// ErrUnknownSchedule is the error for when a learning rate schedule is
+	// requested by a name that isn't registered
+	ErrUnknownSchedule = errors.New("Unknown learning rate schedule")
+)
+
+// Schedule decides the learning rate of each training iteration, starting from
+// the engine's own learning rate
+type Schedule interface {
+	Name() string
+
+	// Rate returns the learning rate for the zero based iteration, given the
+	// initial learning rate
+	Rate(initial float64, iteration int) float64
+}
+
+// ValidationObserver is implemented by schedules that adapt to the validation
+// loss. Observe is called with every validation evaluation during training
+type ValidationObserver interface {
+	Observe(evaluation *Evaluation)
+}
+
+// StatefulSchedule is implemented by schedules that keep state of their own
+// between iterations, which checkpoints save so training resumes exactly
+type StatefulSchedule interface {
+	GetState() (json.RawMessage, error)
+	LoadState(state json.RawMessage) error
+}
+
+// ScheduleByName returns the built-in schedule registered under the given
+// name, using the default parameters for any that are configurable
+func ScheduleByName(name string) (Schedule, error) {
+	switch name {
+	case "constant":
+		return &ConstantSchedule{}, nil
+	case "cosine":
+		return NewCosineAnnealingSchedule(100, 1, 0), nil
+	case "exponential":
+		return NewExponentialDecaySchedule(0.99), nil
+	case "reduce_on_plateau":
+		return NewReduceOnPlateauSchedule(0.1, 10), nil
+	case "step":
+		return NewStepDecaySchedule(0.5, 100), nil
+	case "warmup":
+		return NewWarmupSchedule(100, nil), nil
+	}
+
+	return nil, ErrUnknownSchedule
+}
+
+// ConstantSchedule keeps the initial learning rate throughout
+type ConstantSchedule struct{}
+
+// Name returns the registered name of the schedule
+func (s *ConstantSchedule) Name() string {
+	return "constant"
+}
+
+// Rate returns the initial learning rate
+func (s *ConstantSchedule) Rate(initial float64, iteration int) float64 {
+	return initial
+}
+
+// CosineAnnealingSchedule anneals the learning rate from the initial rate down
+// to MinRate along a half cosine over Period iterations, then restarts. Each
+// period is PeriodMultiplier times longer than the last
+type CosineAnnealingSchedule struct {
+	MinRate          float64 `json:"min_rate"`
+	Period           int     `json:"period"`
+	PeriodMultiplier float64 `json:"period_multiplier"`
+}
+
+// NewCosineAnnealingSchedule creates a new cosine annealing schedule
+func NewCosineAnnealingSchedule(period int, multiplier, minRate float64) *CosineAnnealingSchedule {
+	return &CosineAnnealingSchedule{
+		MinRate:          minRate,
+		Period:           period,
+		PeriodMultiplier: multiplier,
+	}
+}
+
+// Name returns the registered name of the schedule
+func (s *CosineAnnealingSchedule) Name() string {
+	return "cosine"
+}
+
+// Rate returns the annealed learning rate of the iteration within its period
+func (s *CosineAnnealingSchedule) Rate(initial float64, iteration int) float64 {
+	period := math.Max(float64(s.Period), 1)
+	multiplier := math.Max(s.PeriodMultiplier, 1)
+
+	position := float64(iteration)
+	for position >= period {
+		position -= period
+		period *= multiplier
+	}
+
+	return s.MinRate + (initial-s.MinRate)*(1+math.Cos(math.Pi*position/period))/2
+}
+
+// ExponentialDecaySchedule multiplies the learning rate by Decay every
+// iteration
+type ExponentialDecaySchedule struct {
+	Decay float64 `json:"decay"`
+}
+
+// NewExponentialDecaySchedule creates a new exponential decay schedule
+func NewExponentialDecaySchedule(decay float64) *ExponentialDecaySchedule {
+	return &ExponentialDecaySchedule{Decay: decay}
+}
+
+// Name returns the registered name of the schedule
+func (s *ExponentialDecaySchedule) Name() string {
+	return "exponential"
+}
+
+// Rate returns the initial learning rate decayed once for every iteration
+func (s *ExponentialDecaySchedule) Rate(initial float64, iteration int) float64 {
+	return initial * math.Pow(s.Decay, float64(iteration))
+}
+
+// ReduceOnPlateauSchedule multiplies the learning rate by Factor whenever the
+// validation loss hasn't improved by more than MinDelta for Patience
+// evaluations, never going below MinRate. Without validation inputs it keeps
+// the initial learning rate
+type ReduceOnPlateauSchedule struct {
+	Factor   float64 `json:"factor"`
+	MinDelta float64 `json:"min_delta"`
+	MinRate  float64 `json:"min_rate"`
+
+	// Patience is the number of evaluations in a row without improvement that
+	// reduce the rate, counted the same way as EarlyStopping's. With a Patience
+	// of 3, the rate is reduced at every third evaluation that doesn't improve,
+	// and 0 or 1 reduce it at each one
+	Patience int `json:"patience"`
+
+	best       float64
+	observed   bool
+	reductions int
+	wait       int
+}
+
+// NewReduceOnPlateauSchedule creates a new reduce on plateau schedule
+func NewReduceOnPlateauSchedule(factor float64, patience int) *ReduceOnPlateauSchedule {
+	return &ReduceOnPlateauSchedule{Factor: factor, Patience: patience}
+}
+
+// Name returns the registered name of the schedule
+func (s *ReduceOnPlateauSchedule) Name() string {
+	return "reduce_on_plateau"
+}
+
+// Observe tracks the validation loss, reducing the learning rate once it has
+// plateaued
+func (s *ReduceOnPlateauSchedule) Observe(evaluation *Evaluation) {
+	if !s.observed || evaluation.Loss < s.best-s.MinDelta {
+		s.best = evaluation.Loss
+		s.observed = true
+		s.wait = 0
+		return
+	}
+
+	s.wait++
+	if s.wait >= s.Patience {
+		s.reductions++
+		s.wait = 0
+	}
+}
+
+// plateauState is the saved progress of a ReduceOnPlateauSchedule
+type plateauState struct {
+	Best       float64 `json:"best"`
+	Observed   bool    `json:"observed"`
+	Reductions int     `json:"reductions"`
+	Wait       int     `json:"wait"`
+}
+
+// GetState returns the best validation loss seen and how many evaluations and
+// reductions have happened since
+func (s *ReduceOnPlateauSchedule) GetState() (json.RawMessage, error) {
+	return json.Marshal(plateauState{Best: s.best, Observed: s.observed, Reductions: s.reductions, Wait: s.wait})
+}
+
+// LoadState restores the progress returned by GetState
+func (s *ReduceOnPlateauSchedule) LoadState(state json.RawMessage) error {
+	src := plateauState{}
+	if err := json.Unmarshal(state, &src); err != nil {
+		return err
+	}
+
+	s.best, s.observed, s.reductions, s.wait = src.Best, src.Observed, src.Reductions, src.Wait
+	return nil
+}
+
+// Rate returns the initial learning rate reduced by every plateau so far. The
+// reductions stop at MinRate, but never take the rate above the initial one
+func (s *ReduceOnPlateauSchedule) Rate(initial float64, iteration int) float64 {
+	if s.reductions == 0 {
+		return initial
+	}
+
+	return math.Max(initial*math.Pow(s.Factor, float64(s.reductions)), math.Min(s.MinRate, initial))
+}
+
+// StepDecaySchedule multiplies the learning rate by Factor every StepSize
+// iterations. A StepSize below 2 decays the rate on every iteration, and specs
+// reject one below 1
+type StepDecaySchedule struct {
+	Factor   float64 `json:"factor"`
+	StepSize int     `json:"step_size"`
+}
+
+// NewStepDecaySchedule creates a new step decay schedule
+func NewStepDecaySchedule(factor float64, stepSize int) *StepDecaySchedule {
+	return &StepDecaySchedule{Factor: factor, StepSize: stepSize}
+}
+
+// Name returns the registered name of the schedule
+func (s *StepDecaySchedule) Name() string {
+	return "step"
+}
+
+// Rate returns the initial learning rate decayed once for every step taken
+func (s *StepDecaySchedule) Rate(initial float64, iteration int) float64 {
+	steps := iteration
+	if s.StepSize > 1 {
+		steps = iteration / s.StepSize
+	}
+
+	return initial * math.Pow(s.Factor, float64(steps))
+}
+
+// WarmupSchedule ramps the learning rate up linearly from initial / Steps to
+// the initial rate over the first Steps iterations, and then hands over to
+// Schedule, which starts counting iterations from the end of the warmup. A nil
+// Schedule keeps the initial rate after the warmup
+type WarmupSchedule struct {
+	Schedule Schedule `json:"-"`
+	Steps    int      `json:"steps"`
+
+	// iteration is the last iteration a rate was asked for, which tells whether
+	// evaluations come from the warmup
+	iteration int
+}
+
+// NewWarmupSchedule creates a new warmup schedule that hands over to the given
+// schedule
+func NewWarmupSchedule(steps int, schedule Schedule) *WarmupSchedule {
+	return &WarmupSchedule{Schedule: schedule, Steps: steps}
+}
+
+// Name returns the registered name of the schedule
+func (s *WarmupSchedule) Name() string {
+	return "warmup"
+}
+
+// Observe passes the evaluation on to the schedule after the warmup if it
+// adapts to the validation loss. Evaluations of the iterations during the
+// warmup are dropped, since the schedule isn't in charge of the rate yet
+func (s *WarmupSchedule) Observe(evaluation *Evaluation) {
+	if s.iteration < s.Steps {
+		return
+	}
+
+	if observer, ok := s.Schedule.(ValidationObserver); ok {
+		observer.Observe(evaluation)
+	}
+}
+
+// Rate returns the warmed up learning rate, or the rate of the schedule after
+// the warmup
+func (s *WarmupSchedule) Rate(initial float64, iteration int) float64 {
+	s.iteration = iteration
+	if iteration < s.Steps {
+		return initial * float64(iteration+1) / float64(s.Steps)
+	}
+
+	if s.Schedule == nil {
+		return initial
+	}
+
+	return s.Schedule.Rate(initial, iteration-s.Steps)
+}
+
+// GetState returns the state of the wrapped schedule, or nil if it has none
+func (s *WarmupSchedule) GetState() (json.RawMessage, error) {
+	if schedule, ok := s.Schedule.(StatefulSchedule); ok {
+		return schedule.GetState()
+	}
+
+	return nil, nil
+}
+
+// LoadState restores the state of the wrapped schedule
+func (s *WarmupSchedule) LoadState(state json.RawMessage) error {
+	if schedule, ok := s.Schedule.(StatefulSchedule); ok {
+		return schedule.LoadState(state)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package ann
+
+import (
+	"math"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestSchedule(suite *testing.T) {
+	rates := func(schedule Schedule, iterations int) []float64 {
+		rates := make([]float64, iterations)
+		for i := range rates {
+			rates[i] = schedule.Rate(1.0, i)
+		}
+
+		return rates
+	}
+
+	this.Should("Look up the built-in schedules by name", suite,
+		func() {
+			for _, name := range []string{"constant", "cosine", "exponential", "reduce_on_plateau", "step", "warmup"} {
+				schedule, err := ScheduleByName(name)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(schedule.Name()).To(Equal(name))
+			}
+
+			_, err := ScheduleByName("sawtooth")
+			Expect(err).To(Equal(ErrUnknownSchedule))
+		})
+
+	this.Should("Decay the learning rate in steps and exponentially", suite,
+		func() {
+			Expect(rates(&ConstantSchedule{}, 3)).To(Equal([]float64{1, 1, 1}))
+			Expect(rates(NewStepDecaySchedule(0.5, 2), 5)).To(Equal([]float64{1, 1, 0.5, 0.5, 0.25}))
+
+			exponential := rates(NewExponentialDecaySchedule(0.9), 3)
+			Expect(exponential[0]).To(Equal(1.0))
+			Expect(exponential[2]).To(BeNumerically("~", 0.81, 1e-12))
+		})
+
+	this.Should("Anneal along a cosine and restart with longer periods", suite,
+		func() {
+			cosine := rates(NewCosineAnnealingSchedule(4, 2, 0.1), 13)
+			Expect(cosine[0]).To(Equal(1.0))
+			Expect(cosine[2]).To(BeNumerically("~", 0.55, 1e-12))
+			Expect(cosine[3]).To(BeNumerically(">", 0.1))
+
+			// The second period is twice as long, so it's halfway at iteration 8
+			Expect(cosine[4]).To(BeNumerically("~", 1.0, 1e-12))
+			Expect(cosine[8]).To(BeNumerically("~", 0.55, 1e-12))
+			Expect(cosine[12]).To(BeNumerically("~", 1.0, 1e-12))
+		})
+
+	this.Should("Warm up linearly before handing over", suite,
+		func() {
+			Expect(rates(NewWarmupSchedule(4, nil), 6)).To(Equal([]float64{0.25, 0.5, 0.75, 1, 1, 1}))
+
+			warmup := rates(NewWarmupSchedule(2, NewStepDecaySchedule(0.5, 1)), 4)
+			Expect(warmup).To(Equal([]float64{0.5, 1, 1, 0.5}))
+		})
+
+	this.Should("Reduce the learning rate once the validation loss plateaus", suite,
+		func() {
+			plateau := NewReduceOnPlateauSchedule(0.5, 2)
+			plateau.MinRate = 0.2
+			schedule := NewWarmupSchedule(0, plateau)
+
+			for _, loss := range []float64{1, 0.9, 0.95, 0.9} {
+				schedule.Observe(&Evaluation{Loss: loss})
+			}
+			Expect(schedule.Rate(1.0, 10)).To(Equal(0.5))
+
+			for _, loss := range []float64{0.8, 0.8, 0.8} {
+				schedule.Observe(&Evaluation{Loss: loss})
+			}
+			Expect(schedule.Rate(1.0, 10)).To(Equal(0.25))
+
+			for i := 0; i < 10; i++ {
+				schedule.Observe(&Evaluation{Loss: 0.8})
+			}
+			Expect(schedule.Rate(1.0, 10)).To(Equal(0.2))
+		})
+
+	this.Should("Only hold the rate at the minimum once it has been reduced", suite,
+		func() {
+			schedule := NewReduceOnPlateauSchedule(0.5, 1)
+			schedule.MinRate = 0.2
+			Expect(schedule.Rate(0.1, 0)).To(Equal(0.1))
+
+			schedule.Observe(&Evaluation{Loss: 1})
+			schedule.Observe(&Evaluation{Loss: 1})
+			Expect(schedule.Rate(0.1, 1)).To(Equal(0.1))
+			Expect(schedule.Rate(1.0, 1)).To(Equal(0.5))
+
+			schedule.Observe(&Evaluation{Loss: 1})
+			schedule.Observe(&Evaluation{Loss: 1})
+			Expect(schedule.Rate(1.0, 1)).To(Equal(0.2))
+		})
+
+	this.Should("Reduce the rate at the evaluation that runs out of patience", suite,
+		func() {
+			schedule := NewReduceOnPlateauSchedule(0.5, 3)
+			schedule.Observe(&Evaluation{Loss: 1})
+			for i := 0; i < 2; i++ {
+				schedule.Observe(&Evaluation{Loss: 1})
+			}
+			Expect(schedule.Rate(1.0, 0)).To(Equal(1.0))
+
+			schedule.Observe(&Evaluation{Loss: 1})
+			Expect(schedule.Rate(1.0, 0)).To(Equal(0.5))
+		})
+
+	this.Should("Only pass evaluations on to the schedule after the warmup", suite,
+		func() {
+			schedule := NewWarmupSchedule(3, NewReduceOnPlateauSchedule(0.5, 0))
+			for i, loss := range []float64{1, 2, 3} {
+				schedule.Rate(1.0, i)
+				schedule.Observe(&Evaluation{Loss: loss})
+			}
+			Expect(schedule.Rate(1.0, 3)).To(Equal(1.0))
+
+			schedule.Observe(&Evaluation{Loss: 1})
+			schedule.Observe(&Evaluation{Loss: 2})
+			Expect(schedule.Rate(1.0, 4)).To(Equal(0.5))
+		})
+
+	this.Should("Train with the scheduled learning rate and restore the initial one", suite,
+		func() {
+			network := NewNeuralNetworkWithSeed(0, 0, 0, 5)
+			network.AddConfiguredLayer(LayerConfiguration{Width: 2, Height: 1, Activation: &Identity{}})
+			network.AddConfiguredLayer(LayerConfiguration{Width: 1, Height: 1, Activation: &Logistic{}})
+
+			inputs := []*InputConfiguration{
+				&InputConfiguration{Values: [][]float64{{1}, {0}}, Expected: [][]float64{{1}}, Weight: 1},
+			}
+
+			gradient := NewGradientEngine(0.5)
+			evaluator := &DefaultEvaluator{LearningRate: 0.5}
+			for _, engine := range []NetworkEngine{gradient, evaluator} {
+				config := &TrainingConfiguration{
+					Engine:   engine,
+					Inputs:   inputs,
+					Network:  network,
+					Schedule: NewWarmupSchedule(2, NewExponentialDecaySchedule(0.5)),
+				}
+
+				history, err := engine.Train(5, config)
+				Expect(err).ToNot(HaveOccurred())
+				for i, record := range history.Records {
+					Expect(record.LearningRate).To(BeNumerically("~",
+						config.Schedule.Rate(0.5, i), 1e-12))
+				}
+				Expect(history.Records[4].LearningRate).To(BeNumerically("~", 0.5*math.Pow(0.5, 2), 1e-12))
+			}
+
+			Expect(gradient.Optimizer.GetLearningRate()).To(Equal(0.5))
+			Expect(evaluator.GetLearningRate()).To(Equal(0.5))
+		})
+}
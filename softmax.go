@@ -0,0 +1,150 @@
+package ann
+
+import (
+	"math"
+	"sort"
+)
+
+// Softmax normalizes the activated potentials of a layer into a probability
+// distribution, either across the whole grid or separately for each row. It's
+// meant for the output layer of classifiers, paired with the categorical cross
+// entropy loss
+type Softmax struct {
+	// Log produces log probabilities instead, which pair with the negative log
+	// likelihood loss
+	Log    bool `json:"log"`
+	PerRow bool `json:"per_row"`
+}
+
+// Position is the row and column of a neuron within its layer
+type Position struct {
+	Row    int `json:"row"`
+	Column int `json:"column"`
+}
+
+var (
+	// NoPosition is the position ArgMax returns for a layer without neurons
+	NoPosition = Position{Row: -1, Column: -1}
+)
+
+// Name returns the name of the normalization
+func (s *Softmax) Name() string {
+	if s.Log {
+		return "log_softmax"
+	}
+
+	return "softmax"
+}
+
+// apply replaces the potentials of the layer with their softmax
+func (s *Softmax) apply(layer *NetworkLayer) {
+	for _, group := range s.groups(layer) {
+		values := make([]float64, len(group))
+		for i, n := range group {
+			values[i] = n.Potential
+		}
+
+		s.normalize(values)
+		for i, n := range group {
+			n.Potential = values[i]
+		}
+	}
+}
+
+// normalize replaces a group of potentials with their softmax
+func (s *Softmax) normalize(values []float64) {
+	max := math.Inf(-1)
+	for _, value := range values {
+		max = math.Max(max, value)
+	}
+
+	// Shift by the largest potential so the exponentials can't overflow
+	sum := 0.0
+	for _, value := range values {
+		sum += math.Exp(value - max)
+	}
+
+	logSum := max + math.Log(sum)
+	for i, value := range values {
+		if s.Log {
+			values[i] = value - logSum
+		} else {
+			values[i] = math.Exp(value - logSum)
+		}
+	}
+}
+
+// backward turns the gradients of the loss with respect to the normalized
+// potentials into gradients with respect to the potentials before the softmax
+func (s *Softmax) backward(layer *NetworkLayer, grads map[*Neuron]float64) {
+	for _, group := range s.groups(layer) {
+		if s.Log {
+			sum := 0.0
+			for _, n := range group {
+				sum += grads[n]
+			}
+
+			for _, n := range group {
+				grads[n] -= math.Exp(n.Potential) * sum
+			}
+		} else {
+			dot := 0.0
+			for _, n := range group {
+				dot += grads[n] * n.Potential
+			}
+
+			for _, n := range group {
+				grads[n] = n.Potential * (grads[n] - dot)
+			}
+		}
+	}
+}
+
+// groups returns the sets of neurons that are each normalized together
+func (s *Softmax) groups(layer *NetworkLayer) [][]*Neuron {
+	if s.PerRow {
+		return layer.Neurons
+	}
+
+	all := make([]*Neuron, 0, layer.Width()*layer.Height())
+	layer.EachNeuron(func(n *Neuron) {
+		all = append(all, n)
+	})
+
+	return [][]*Neuron{all}
+}
+
+// ArgMax returns the position of the neuron with the highest potential in the
+// layer, or NoPosition if the layer has no neurons
+func (l *NetworkLayer) ArgMax() Position {
+	top := l.TopK(1)
+	if len(top) == 0 {
+		return NoPosition
+	}
+
+	return top[0]
+}
+
+// TopK returns the positions of the k neurons with the highest potentials in
+// the layer, from highest to lowest. Ties keep their order in the grid. k is
+// clamped to the number of neurons, and anything below 1 gives no positions
+func (l *NetworkLayer) TopK(k int) []Position {
+	positions := make([]Position, 0)
+	l.EachNeuronWithIndex(func(n *Neuron, row, column int) {
+		positions = append(positions, Position{Row: row, Column: column})
+	})
+
+	sort.SliceStable(positions, func(i, j int) bool {
+		a, b := positions[i], positions[j]
+		return l.Neurons[a.Row][a.Column].Potential > l.Neurons[b.Row][b.Column].Potential
+	})
+
+	if k < 0 {
+		k = 0
+	}
+	if k < len(positions) {
+		positions = positions[:k]
+	}
+
+	return positions
+}
@@ -0,0 +1,161 @@
+package ann
+
+import (
+	"math"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestSoftmax(suite *testing.T) {
+	this.Before(suite, func() {
+		InhibitoryNeuronDensity = 0.0
+	})
+
+	input := [][]float64{
+		[]float64{0.1, 0.9, 1.03},
+		[]float64{0.51, 0.5, 0.5},
+		[]float64{0.9, 0.85, 0.01},
+	}
+
+	newNetwork := func(softmax *Softmax) *NeuralNetwork {
+		return newTestNetwork(1,
+			LayerConfiguration{Width: 3, Height: 3, Activation: &Identity{}},
+			LayerConfiguration{Width: 4, Height: 4, Activation: &Tanh{}},
+			LayerConfiguration{Width: 2, Height: 3, Activation: &Identity{}, Softmax: softmax})
+	}
+
+	this.Should("Normalize the whole grid or each row into a distribution", suite,
+		func() {
+			network := newNetwork(&Softmax{})
+			Expect(network.Run(input)).To(Succeed())
+
+			total := 0.0
+			network.GetOutput().EachNeuron(func(n *Neuron) {
+				Expect(n.Potential).To(BeNumerically(">", 0))
+				total += n.Potential
+			})
+			Expect(total).To(BeNumerically("~", 1.0, 1e-12))
+
+			network.GetOutput().Softmax = &Softmax{PerRow: true}
+			Expect(network.Run(input)).To(Succeed())
+			for _, row := range network.GetOutput().Neurons {
+				total := 0.0
+				for _, n := range row {
+					total += n.Potential
+				}
+				Expect(total).To(BeNumerically("~", 1.0, 1e-12))
+			}
+		})
+
+	this.Should("Produce log probabilities with a log softmax", suite,
+		func() {
+			network := newNetwork(&Softmax{})
+			network.Run(input)
+			probs := network.GetOutput().TopK(6)
+			expected := make([]float64, len(probs))
+			for i, pos := range probs {
+				expected[i] = math.Log(network.GetOutput().Neurons[pos.Row][pos.Column].Potential)
+			}
+
+			network.GetOutput().Softmax = &Softmax{Log: true}
+			network.Run(input)
+			for i, pos := range probs {
+				Expect(network.GetOutput().Neurons[pos.Row][pos.Column].Potential).To(
+					BeNumerically("~", expected[i], 1e-12))
+			}
+		})
+
+	this.Should("Stay finite for large potentials", suite,
+		func() {
+			layer := NewNetworkLayerWithDensity(1, 3, 0)
+			layer.SetActivation(&Identity{})
+			layer.Softmax = &Softmax{}
+			for i, n := range layer.Neurons[0] {
+				n.Potential = 1000.0 * float64(i+1)
+			}
+
+			layer.Activate()
+			Expect(layer.Neurons[0][2].Potential).To(BeNumerically("~", 1.0, 1e-12))
+			Expect(math.IsNaN(layer.Neurons[0][0].Potential)).To(BeFalse())
+		})
+
+	this.Should("Find the winning positions with ArgMax and TopK", suite,
+		func() {
+			layer := NewNetworkLayerWithDensity(2, 3, 0)
+			values := [][]float64{[]float64{0.1, 0.7, 0.3}, []float64{0.9, 0.3, 0.0}}
+			layer.EachNeuronWithIndex(func(n *Neuron, row, column int) {
+				n.Potential = values[row][column]
+			})
+
+			Expect(layer.ArgMax()).To(Equal(Position{Row: 1, Column: 0}))
+			Expect(layer.TopK(3)).To(Equal([]Position{
+				Position{Row: 1, Column: 0}, Position{Row: 0, Column: 1}, Position{Row: 0, Column: 2}}))
+			Expect(layer.TopK(10)).To(HaveLen(6))
+			Expect(layer.TopK(0)).To(BeEmpty())
+			Expect(layer.TopK(-1)).To(BeEmpty())
+
+			empty := &NetworkLayer{Neurons: [][]*Neuron{}}
+			Expect(empty.TopK(3)).To(BeEmpty())
+			Expect(empty.ArgMax()).To(Equal(NoPosition))
+
+			network := newNetwork(&Softmax{})
+			network.Run(input)
+			Expect(network.ArgMax()).To(Equal(network.TopK(1)[0]))
+		})
+
+	this.Should("Calculate gradients through the softmax that match a finite difference estimate", suite,
+		func() {
+			expected := [][]float64{[]float64{0, 0, 1}, []float64{0, 0, 0}}
+			perRow := [][]float64{[]float64{0, 0, 1}, []float64{1, 0, 0}}
+
+			cases := []struct {
+				softmax  *Softmax
+				expected [][]float64
+			}{
+				{&Softmax{}, expected},
+				{&Softmax{Log: true}, expected},
+				{&Softmax{PerRow: true}, perRow},
+				{&Softmax{Log: true, PerRow: true}, perRow},
+			}
+
+			for _, c := range cases {
+				network := newNetwork(c.softmax)
+				engine := NewGradientEngine(0.5)
+				loss := DefaultLoss(network.GetOutput())
+
+				totalLoss := func() float64 {
+					engine.Run(input, network)
+					return LayerLoss(loss, c.expected, network.GetOutput())
+				}
+
+				totalLoss()
+				grads := NewGradients()
+				Expect(engine.CalculateGradientsWithLoss(c.expected, network, loss, grads)).To(Succeed())
+
+				epsilon := 1e-6
+				for conn, grad := range grads.Weights {
+					orig := conn.Weight
+					conn.Weight = orig + epsilon
+					plus := totalLoss()
+					conn.Weight = orig - epsilon
+					minus := totalLoss()
+					conn.Weight = orig
+
+					Expect(grad).To(BeNumerically("~", (plus-minus)/(2*epsilon), 1e-6))
+				}
+			}
+		})
+
+	this.Should("Pair the default loss with the output layer", suite,
+		func() {
+			Expect(DefaultLoss(newNetwork(nil).GetOutput())).To(Equal(&MeanSquaredLoss{}))
+			Expect(DefaultLoss(newNetwork(&Softmax{}).GetOutput())).To(Equal(&CategoricalCrossEntropyLoss{}))
+			Expect(DefaultLoss(newNetwork(&Softmax{Log: true}).GetOutput())).To(
+				Equal(&NegativeLogLikelihoodLoss{}))
+
+			config := &TrainingConfiguration{Network: newNetwork(&Softmax{})}
+			Expect(config.GetLoss()).To(Equal(&CategoricalCrossEntropyLoss{}))
+		})
+}
@@ -1,19 +1,210 @@
-package main
+package ann
 
-import "math/rand"
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+const (
+	// SamplingWeighted picks inputs at random, proportional to their weight
+	SamplingWeighted = iota
+
+	// SamplingEpoch visits every input exactly once per iteration, in a freshly
+	// shuffled order each time
+	SamplingEpoch = iota
+)
+
+const (
+	// FullBatch is the batch size for accumulating every input into a single
+	// update
+	FullBatch = -1
+)
+
+var (
+	// ErrNoInputs is the error for training without any inputs to train on
+	ErrNoInputs = errors.New("No inputs to train on")
+)
 
 // TrainingConfiguration the setup for running training simulations
 type TrainingConfiguration struct {
-	Debug   bool `json:"debug"`
-	Engine  NetworkEngine
-	Inputs  []*InputConfiguration `json:"inputs"`
-	Network NetworkConfiguration  `json:"network"`
+	// BatchSize is the number of inputs whose gradients are accumulated before
+	// each update. Zero and one both mean updating after every input, while
+	// FullBatch uses the whole set of inputs for every update
+	BatchSize int  `json:"batch_size"`
+	Debug     bool `json:"debug"`
+
+	// EarlyStopping stops training once the validation loss stops improving,
+	// restoring the best weights
+	EarlyStopping *EarlyStopping        `json:"early_stopping"`
+	Engine        NetworkEngine         `json:"-"`
+	Inputs        []*InputConfiguration `json:"inputs"`
+
+	// Iteration is the number of iterations trained so far. Train counts on
+	// from it, so the schedule, validation and history of a later run pick up
+	// where the last one, or a resumed checkpoint, left off
+	Iteration int `json:"iteration"`
+
+	// Loss is used for both the training updates and the reported error,
+	// defaulting to the DefaultLoss of the network's output layer
+	Loss    Loss                 `json:"-"`
+	Network NetworkConfiguration `json:"network"`
+
+	// Rollback restores the weights from the start of an iteration that leaves
+	// any of them, or any potential, no longer finite. Training stops with a
+	// NumericError either way
+	Rollback bool `json:"rollback"`
+
+	// Sampling is how inputs are chosen, either SamplingWeighted or
+	// SamplingEpoch. With SamplingEpoch, each training iteration is one epoch
+	Sampling int `json:"sampling"`
+
+	// Schedule sets the engine's learning rate for each iteration, starting
+	// from the rate the engine was created with. Leaving it nil keeps that rate
+	Schedule Schedule `json:"-"`
+
+	// Validation is a held-out set of inputs that is evaluated every
+	// ValidationInterval iterations, defaulting to every iteration
+	Validation         []*InputConfiguration `json:"validation"`
+	ValidationInterval int                   `json:"validation_interval"`
+}
+
+// SamplingByName returns the sampling mode with the given name, either
+// "weighted" or "epoch". An empty name is weighted sampling
+func SamplingByName(name string) (int, error) {
+	switch name {
+	case "", "weighted":
+		return SamplingWeighted, nil
+	case "epoch":
+		return SamplingEpoch, nil
+	}
+
+	return 0, fmt.Errorf("Unknown sampling mode %q", name)
+}
+
+// Batches returns the batches of inputs to train on for a single iteration.
+// Weighted sampling yields a single batch of randomly picked inputs, while
+// epoch sampling splits a shuffled copy of every input into batches
+func (t *TrainingConfiguration) Batches() [][]*InputConfiguration {
+	if t.BatchSize == FullBatch && t.Sampling != SamplingEpoch {
+		return [][]*InputConfiguration{t.Inputs}
+	}
+
+	size := t.BatchSize
+	if size == FullBatch || size > len(t.Inputs) && t.Sampling == SamplingEpoch {
+		size = len(t.Inputs)
+	} else if size < 1 {
+		size = 1
+	}
+
+	if t.Sampling != SamplingEpoch {
+		batch := make([]*InputConfiguration, size)
+		for i := range batch {
+			batch[i] = t.PickInput()
+		}
+
+		return [][]*InputConfiguration{batch}
+	}
+
+	order := t.random().Perm(len(t.Inputs))
+	batches := make([][]*InputConfiguration, 0, (len(order)+size-1)/size)
+	for start := 0; start < len(order); start += size {
+		end := start + size
+		if end > len(order) {
+			end = len(order)
+		}
+
+		batch := make([]*InputConfiguration, 0, end-start)
+		for _, i := range order[start:end] {
+			batch = append(batch, t.Inputs[i])
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
+
+// CheckEngine returns an error for settings the configured engine can't train
+// with, rather than letting them be quietly ignored. Only the DefaultEvaluator
+// has such settings, see checkDefaultEvaluator
+func (t *TrainingConfiguration) CheckEngine() error {
+	if _, ok := t.Engine.(*DefaultEvaluator); ok {
+		return t.checkDefaultEvaluator()
+	}
+
+	return nil
+}
+
+// checkDefaultEvaluator returns an error for settings the DefaultEvaluator
+// can't train with. It adjusts the weights its own way after every input and
+// runs the network as is, so it can't train in batches, regularize the weights
+// or drop out neurons and connections
+func (t *TrainingConfiguration) checkDefaultEvaluator() error {
+	if t.BatchSize > 1 || t.BatchSize == FullBatch {
+		return fmt.Errorf("The default engine doesn't support batches, it updates after every input")
+	}
+
+	if t.Network == nil {
+		return nil
+	}
+
+	for i, layer := range t.Network.GetLayers() {
+		if layer.Regularization != nil && *layer.Regularization != (Regularization{}) {
+			return fmt.Errorf("Layer %d: the default engine doesn't support regularization", i)
+		}
+		if layer.Dropout != 0 || layer.DropConnect != 0 {
+			return fmt.Errorf("Layer %d: the default engine doesn't support dropout", i)
+		}
+	}
+
+	return nil
+}
+
+// GetLoss returns the configured loss, or the DefaultLoss of the network's
+// output layer if there isn't one
+func (t *TrainingConfiguration) GetLoss() Loss {
+	if t.Loss == nil {
+		if t.Network == nil {
+			return DefaultLoss(nil)
+		}
+
+		return DefaultLoss(t.Network.GetOutput())
+	}
+
+	return t.Loss
+}
+
+// scheduledRate returns the learning rate to train the iteration with
+func (t *TrainingConfiguration) scheduledRate(initial float64, iteration int) float64 {
+	if t.Schedule == nil {
+		return initial
+	}
+
+	return t.Schedule.Rate(initial, iteration)
+}
+
+// observe passes a validation evaluation on to the schedule if it adapts to
+// the validation loss
+func (t *TrainingConfiguration) observe(evaluation *Evaluation) {
+	if observer, ok := t.Schedule.(ValidationObserver); ok && evaluation != nil {
+		observer.Observe(evaluation)
+	}
+}
+
+// random returns the network's random source, falling back to the global one
+// without a network
+func (t *TrainingConfiguration) random() *rand.Rand {
+	if t.Network == nil {
+		return globalRand
+	}
+
+	return t.Network.GetRand()
 }
 
 // PickInput picks a random input from the training set based on their given
 // proportional weight
 func (t *TrainingConfiguration) PickInput() *InputConfiguration {
-	pick := rand.Float64()
+	pick := t.random().Float64()
 	currWeight := 0.0
 
 	for i, input := range t.Inputs {
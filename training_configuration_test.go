@@ -0,0 +1,111 @@
+package ann
+
+import (
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestTrainingBatches(suite *testing.T) {
+	newInputs := func(count int) []*InputConfiguration {
+		inputs := make([]*InputConfiguration, count)
+		for i := range inputs {
+			val := float64(i) / float64(count)
+			inputs[i] = &InputConfiguration{
+				Expected: [][]float64{[]float64{1 - val}},
+				Values:   [][]float64{[]float64{val, 1 - val}},
+				Weight:   1.0,
+			}
+		}
+
+		return inputs
+	}
+
+	this.Should("Pick a single weighted batch of the configured size", suite,
+		func() {
+			config := &TrainingConfiguration{Inputs: newInputs(5)}
+			batches := config.Batches()
+			Expect(batches).To(HaveLen(1))
+			Expect(batches[0]).To(HaveLen(1))
+
+			config.BatchSize = 3
+			Expect(config.Batches()[0]).To(HaveLen(3))
+
+			config.BatchSize = FullBatch
+			Expect(config.Batches()[0]).To(Equal(config.Inputs))
+		})
+
+	this.Should("Visit every input once per epoch", suite,
+		func() {
+			config := &TrainingConfiguration{
+				BatchSize: 3,
+				Inputs:    newInputs(8),
+				Sampling:  SamplingEpoch,
+			}
+
+			batches := config.Batches()
+			Expect(batches).To(HaveLen(3))
+			Expect(batches[2]).To(HaveLen(2))
+
+			seen := make(map[*InputConfiguration]int)
+			for _, batch := range batches {
+				for _, input := range batch {
+					seen[input]++
+				}
+			}
+
+			Expect(seen).To(HaveLen(8))
+			for _, count := range seen {
+				Expect(count).To(Equal(1))
+			}
+
+			config.BatchSize = FullBatch
+			Expect(config.Batches()).To(HaveLen(1))
+			Expect(config.Batches()[0]).To(ConsistOf(config.Inputs))
+		})
+
+	this.Should("Apply the mean gradient of a full batch as a single update", suite,
+		func() {
+			network := NewNeuralNetwork(0, 0, 0)
+			network.AddConfiguredLayer(LayerConfiguration{
+				Width: 1, Height: 2, Activation: &Identity{}})
+			network.AddConfiguredLayer(LayerConfiguration{
+				Width: 1, Height: 1, Activation: &Tanh{}})
+
+			inputs := newInputs(4)
+			engine := NewGradientEngine(0.5)
+			expected := network.Clone()
+
+			// Work out the averaged update by hand on the clone
+			grads := NewGradients()
+			for _, input := range inputs {
+				engine.Run(input.Values, expected)
+				engine.CalculateGradients(input.Expected, expected, grads)
+			}
+			engine.ApplyGradients(grads, len(inputs))
+
+			engine.Train(1, &TrainingConfiguration{
+				BatchSize: FullBatch,
+				Inputs:    inputs,
+				Network:   network,
+			})
+
+			for i, conn := range network.GetOutput().Neurons[0][0].In {
+				Expect(conn.Weight).To(BeNumerically("~",
+					expected.GetOutput().Neurons[0][0].In[i].Weight, 1e-12))
+			}
+			Expect(network.GetOutput().Neurons[0][0].Bias).To(BeNumerically("~",
+				expected.GetOutput().Neurons[0][0].Bias, 1e-12))
+		})
+
+	this.Should("Reject unknown sampling modes", suite,
+		func() {
+			sampling, err := SamplingByName("epoch")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sampling).To(Equal(SamplingEpoch))
+
+			_, err = SamplingByName("lottery")
+			Expect(err).To(HaveOccurred())
+		})
+}
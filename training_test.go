@@ -1,4 +1,4 @@
-package main
+package ann
 
 import (
 	"math"
@@ -161,7 +161,7 @@ func TestComplicatedTraining(suite *testing.T) {
 			// We ran for a while, there should be less than 0.001 worth of error
 			// in our network
 			Expect(int(totalError * 1000.0)).To(Equal(0))
-			Debug.Println("Total error:", totalError)
+			debugLog.Println("Total error:", totalError)
 		})
 
 }
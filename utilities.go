@@ -1,4 +1,4 @@
-package main
+package ann
 
 import "math"
 
@@ -9,6 +9,15 @@ func round(src float64, digits int) float64 {
 	return float64(int32(src*mult)) / mult
 }
 
+// abs returns the absolute value of an integer
+func abs(val int) int {
+	if val < 0 {
+		return -val
+	}
+
+	return val
+}
+
 func sigmoid(val float64) float64 {
 	return 1.0 / sigmoidBase(val)
 }
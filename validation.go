@@ -0,0 +1,196 @@
+package ann
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrNoValidationInputs is the error for when a validation split would leave
+	// no inputs to train on
+	ErrNoValidationInputs = errors.New("Validation split leaves no inputs to train on")
+)
+
+// EarlyStopping stops training once the validation loss stops improving and
+// restores the weights from the best evaluation
+type EarlyStopping struct {
+	// MinDelta is how much the validation loss has to drop to count as an
+	// improvement
+	MinDelta float64 `json:"min_delta"`
+
+	// Patience is the number of evaluations in a row without improvement that
+	// stop training, counted the same way as ReduceOnPlateauSchedule's. With a
+	// Patience of 3, training stops at the third evaluation that doesn't
+	// improve, and 0 or 1 stop at the first one
+	Patience int `json:"patience"`
+}
+
+// Evaluation is the performance of a network on a set of inputs
+type Evaluation struct {
+	// Accuracy is the fraction of inputs the network classifies correctly, as
+	// in a ClassificationReport
+	Accuracy float64 `json:"accuracy"`
+
+	// Loss is the average loss per input
+	Loss float64 `json:"loss"`
+}
+
+// Evaluate runs the network against every input and measures its average loss
+// and accuracy. Inputs are run with the configured engine, if there is one
+func (t *TrainingConfiguration) Evaluate(inputs []*InputConfiguration) (*Evaluation, error) {
+	eval := &Evaluation{}
+	if len(inputs) == 0 {
+		return eval, nil
+	}
+
+	loss := t.GetLoss()
+	output := t.Network.GetOutput()
+	for _, input := range inputs {
+		var err error
+		if t.Engine != nil {
+			err = t.Engine.Run(input.Values, t.Network)
+		} else {
+			err = t.Network.Run(input.Values)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(input.Expected) != output.Width() ||
+			len(input.Expected[0]) != output.Height() {
+			return nil, ErrArraySizeMismatch
+		}
+
+		eval.Loss += LayerLoss(loss, input.Expected, output)
+		if classifiedCorrectly(output, input.Expected) {
+			eval.Accuracy++
+		}
+	}
+
+	eval.Loss /= float64(len(inputs))
+	eval.Accuracy /= float64(len(inputs))
+
+	return eval, nil
+}
+
+// classifiedCorrectly reports whether the output layer picked the expected
+// class
+func classifiedCorrectly(output *NetworkLayer, expected [][]float64) bool {
+	return predictedClass(output) == expectedClass(expected)
+}
+
+// SplitValidation moves a random fraction of the inputs into the validation
+// set, picked the same way every time for the same network seed
+func (t *TrainingConfiguration) SplitValidation(fraction float64) error {
+	count := int(math.Round(fraction * float64(len(t.Inputs))))
+	if count <= 0 {
+		return nil
+	}
+
+	if count >= len(t.Inputs) {
+		return ErrNoValidationInputs
+	}
+
+	// The split draws from a source of its own, seeded like the network's, so
+	// a resumed run holds out the same inputs without disturbing the training
+	// draws
+	rng := globalRand
+	if t.Network != nil {
+		rng = newRand(t.Network.GetSeed())
+	}
+
+	order := rng.Perm(len(t.Inputs))
+	inputs := make([]*InputConfiguration, 0, len(t.Inputs)-count)
+	for i, index := range order {
+		if i < count {
+			t.Validation = append(t.Validation, t.Inputs[index])
+		} else {
+			inputs = append(inputs, t.Inputs[index])
+		}
+	}
+	t.Inputs = inputs
+
+	return nil
+}
+
+// validationTracker evaluates the validation set during a training run and
+// keeps a copy of the best network for early stopping
+type validationTracker struct {
+	config *TrainingConfiguration
+	best   float64
+	saved  NetworkConfiguration
+	waited int
+}
+
+// newValidationTracker creates a tracker for a new training run
+func (t *TrainingConfiguration) newValidationTracker() *validationTracker {
+	return &validationTracker{config: t, best: math.Inf(1)}
+}
+
+// step evaluates the validation set if it's due after the given iteration,
+// returning the evaluation and whether training should stop early. An error
+// running the validation set ends the training run, so it's returned instead
+func (v *validationTracker) step(iteration int) (*Evaluation, bool, error) {
+	config := v.config
+	interval := config.ValidationInterval
+	if interval < 1 {
+		interval = 1
+	}
+
+	if len(config.Validation) == 0 || (iteration+1)%interval != 0 {
+		return nil, false, nil
+	}
+
+	eval, err := config.Evaluate(config.Validation)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if config.Debug {
+		debugLog.Printf("Validation loss: %.3f, accuracy: %.3f\n", eval.Loss, eval.Accuracy)
+	}
+
+	stopping := config.EarlyStopping
+	if stopping == nil {
+		return eval, false, nil
+	}
+
+	if eval.Loss < v.best-stopping.MinDelta {
+		v.best = eval.Loss
+		v.saved = config.Network.Clone()
+		v.waited = 0
+		return eval, false, nil
+	}
+
+	v.waited++
+	if v.waited >= stopping.Patience {
+		if config.Debug {
+			debugLog.Printf("Stopping early after iteration %d\n", iteration)
+		}
+
+		return eval, true, nil
+	}
+
+	return eval, false, nil
+}
+
+// finish restores the weights and biases of the best network seen
+func (v *validationTracker) finish() {
+	if v.saved == nil {
+		return
+	}
+
+	network := v.config.Network
+	saved := v.saved.GetLayers()
+	for i, layer := range network.GetLayers() {
+		layer.EachNeuronWithIndex(func(n *Neuron, row, column int) {
+			src := saved[i].Neurons[row][column]
+			n.Bias = src.Bias
+			for j, conn := range n.In {
+				conn.Connections = src.In[j].Connections
+				conn.Weight = src.In[j].Weight
+			}
+		})
+	}
+}
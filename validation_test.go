@@ -0,0 +1,180 @@
+package ann
+
+import (
+	"math"
+	"testing"
+
+	"github.com/connerhansen/this"
+	. "github.com/onsi/gomega"
+)
+
+func TestValidation(suite *testing.T) {
+	newNetwork := func(seed int64) *NeuralNetwork {
+		return newTestNetwork(seed,
+			LayerConfiguration{Width: 2, Height: 1, Activation: &Identity{}},
+			LayerConfiguration{Width: 3, Height: 1, Activation: &Tanh{}},
+			LayerConfiguration{Width: 1, Height: 1, Activation: &Logistic{}})
+	}
+
+	newInput := func(a, b, expected float64) *InputConfiguration {
+		return &InputConfiguration{
+			Values:   [][]float64{[]float64{a}, []float64{b}},
+			Expected: [][]float64{[]float64{expected}},
+			Weight:   1,
+		}
+	}
+
+	this.Should("Evaluate the average loss and accuracy of a set of inputs", suite,
+		func() {
+			network := newNetwork(1)
+			network.GetOutput().Neurons[0][0].In[0].Weight = 0
+			config := &TrainingConfiguration{Network: network, Loss: &MeanAbsoluteLoss{}}
+
+			network.Run([][]float64{[]float64{1}, []float64{0}})
+			output := network.GetOutput().Neurons[0][0].Potential
+			expected := 1.0
+			if output >= 0.5 {
+				expected = 0.0
+			}
+
+			eval, err := config.Evaluate([]*InputConfiguration{
+				newInput(1, 0, 1-expected), newInput(1, 0, expected)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(eval.Accuracy).To(Equal(0.5))
+			Expect(eval.Loss).To(BeNumerically("~",
+				0.5*(math.Abs(output-1+expected)+math.Abs(output-expected)), 1e-12))
+		})
+
+	this.Should("Compare the winning classes of larger output layers", suite,
+		func() {
+			layer := NewNetworkLayerWithDensity(2, 2, 0)
+			layer.Neurons[1][0].Potential = 0.9
+			Expect(classifiedCorrectly(layer, [][]float64{[]float64{0, 0}, []float64{1, 0}})).To(BeTrue())
+			Expect(classifiedCorrectly(layer, [][]float64{[]float64{0, 1}, []float64{0, 0}})).To(BeFalse())
+		})
+
+	this.Should("Split a fraction of the inputs into a validation set", suite,
+		func() {
+			inputs := make([]*InputConfiguration, 10)
+			for i := range inputs {
+				inputs[i] = newInput(float64(i), 0, 0)
+			}
+
+			config := &TrainingConfiguration{Inputs: inputs, Network: newNetwork(1)}
+			Expect(config.SplitValidation(0.3)).To(Succeed())
+			Expect(config.Inputs).To(HaveLen(7))
+			Expect(config.Validation).To(HaveLen(3))
+			for _, input := range config.Validation {
+				Expect(config.Inputs).ToNot(ContainElement(input))
+			}
+
+			config = &TrainingConfiguration{Inputs: inputs, Network: newNetwork(1)}
+			Expect(config.SplitValidation(1.0)).To(Equal(ErrNoValidationInputs))
+		})
+
+	this.Should("Stop training when the validation set can't be run", suite,
+		func() {
+			for _, engine := range []NetworkEngine{NewGradientEngine(0.5), Evaluator} {
+				config := &TrainingConfiguration{
+					Engine:     engine,
+					Inputs:     []*InputConfiguration{newInput(1, 0, 1)},
+					Network:    newNetwork(1),
+					Validation: []*InputConfiguration{&InputConfiguration{Values: [][]float64{{1}}, Weight: 1}},
+				}
+
+				history, err := engine.Train(5, config)
+				Expect(err).To(Equal(ErrArraySizeMismatch))
+				Expect(history.Records).To(BeEmpty())
+			}
+		})
+
+	this.Should("Stop at the evaluation that runs out of patience", suite,
+		func() {
+			// Nothing improves on the first evaluation by 100, so every patience
+			// counts the evaluations after it
+			for _, patience := range []int{0, 1, 3} {
+				config := &TrainingConfiguration{
+					EarlyStopping: &EarlyStopping{Patience: patience, MinDelta: 100},
+					Engine:        NewGradientEngine(0.5),
+					Inputs:        []*InputConfiguration{newInput(1, 0, 1)},
+					Network:       newNetwork(5),
+					Validation:    []*InputConfiguration{newInput(1, 0, 0)},
+				}
+
+				history, err := config.Engine.Train(50, config)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(history.StoppedEarly).To(BeTrue())
+
+				expected := 1 + patience
+				if patience == 0 {
+					expected = 2
+				}
+				Expect(history.Records).To(HaveLen(expected), "patience %d", patience)
+			}
+		})
+
+	this.Should("Stop early and restore the best weights", suite,
+		func() {
+			// Training pushes the output towards 1 while validation wants 0, so the
+			// first evaluation is the best one
+			train := []*InputConfiguration{newInput(1, 0, 1)}
+			validation := []*InputConfiguration{newInput(1, 0, 0)}
+
+			newConfig := func(stopping *EarlyStopping) *TrainingConfiguration {
+				return &TrainingConfiguration{
+					BatchSize:     FullBatch,
+					EarlyStopping: stopping,
+					Engine:        NewGradientEngine(0.5),
+					Inputs:        train,
+					Network:       newNetwork(5),
+					Validation:    validation,
+				}
+			}
+
+			once := newConfig(nil)
+			once.Engine.Train(1, once)
+			best, _ := once.Evaluate(validation)
+
+			full := newConfig(nil)
+			full.Engine.Train(50, full)
+			last, _ := full.Evaluate(validation)
+			Expect(last.Loss).To(BeNumerically(">", best.Loss))
+
+			stopped := newConfig(&EarlyStopping{Patience: 3})
+			stopped.Engine.Train(50, stopped)
+			restored, _ := stopped.Evaluate(validation)
+			Expect(restored.Loss).To(Equal(best.Loss))
+		})
+
+	this.Should("Stop early with the default evaluator as well", suite,
+		func() {
+			newConfig := func(stopping *EarlyStopping) *TrainingConfiguration {
+				return &TrainingConfiguration{
+					EarlyStopping:      stopping,
+					Engine:             Evaluator,
+					Inputs:             []*InputConfiguration{newInput(1, 0, 1)},
+					Network:            newNetwork(5),
+					Validation:         []*InputConfiguration{newInput(1, 0, 0)},
+					ValidationInterval: 2,
+				}
+			}
+
+			// Nothing improves on the first evaluation by 10, so the network ends up
+			// as it was after the first two iterations
+			twice := newConfig(nil)
+			twice.Engine.Train(2, twice)
+
+			stopped := newConfig(&EarlyStopping{Patience: 1, MinDelta: 10})
+			stopped.Engine.Train(100, stopped)
+
+			expected := twice.Network.GetLayers()
+			for i, layer := range stopped.Network.GetLayers() {
+				layer.EachNeuronWithIndex(func(n *Neuron, row, column int) {
+					Expect(n.Bias).To(Equal(expected[i].Neurons[row][column].Bias))
+					for j, conn := range n.In {
+						Expect(conn.Weight).To(Equal(expected[i].Neurons[row][column].In[j].Weight))
+					}
+				})
+			}
+		})
+}